@@ -0,0 +1,120 @@
+// Package apperrors holds the errors the API reports to its clients. The
+// kind of an error decides its HTTP status and its code is stable, clients
+// can branch on it. The message sent along is the code in lower case looked
+// up in the message catalog of utils.
+package apperrors
+
+import (
+	"net/http"
+	"strings"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindUnsupportedMediaType
+	KindValidation
+	KindTooManyRequests
+)
+
+var statuses = map[Kind]int{
+	KindInternal:             http.StatusInternalServerError,
+	KindBadRequest:           http.StatusBadRequest,
+	KindUnauthorized:         http.StatusUnauthorized,
+	KindForbidden:            http.StatusForbidden,
+	KindNotFound:             http.StatusNotFound,
+	KindConflict:             http.StatusConflict,
+	KindTooLarge:             http.StatusRequestEntityTooLarge,
+	KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	KindValidation:           http.StatusUnprocessableEntity,
+	KindTooManyRequests:      http.StatusTooManyRequests,
+}
+
+type Error struct {
+	Kind Kind
+	Code string
+	// Params fill the parameters of the message, as name, value pairs.
+	Params []string
+	// Err is the cause. It is never sent to clients, except for the field
+	// errors of a validation error.
+	Err error
+}
+
+func New(kind Kind, code string, params ...string) *Error {
+	return &Error{Kind: kind, Code: code, Params: params}
+}
+
+func BadRequest(code string, params ...string) *Error {
+	return New(KindBadRequest, code, params...)
+}
+
+func Unauthorized(code string, params ...string) *Error {
+	return New(KindUnauthorized, code, params...)
+}
+
+func Forbidden(code string, params ...string) *Error {
+	return New(KindForbidden, code, params...)
+}
+
+func NotFound(code string, params ...string) *Error {
+	return New(KindNotFound, code, params...)
+}
+
+func Conflict(code string, params ...string) *Error {
+	return New(KindConflict, code, params...)
+}
+
+func TooManyRequests(code string, params ...string) *Error {
+	return New(KindTooManyRequests, code, params...)
+}
+
+// Validation reports the input errors in err, usually utils.FieldErrors.
+func Validation(err error) *Error {
+	return &Error{Kind: KindValidation, Code: "VALIDATION_FAILED", Err: err}
+}
+
+// Internal hides err from clients behind a generic message.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "INTERNAL_ERROR", Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is match errors of the same kind and code, whatever their
+// cause.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func (e *Error) Status() int {
+	return statuses[e.Kind]
+}
+
+// MessageKey is the key of the message of e in the message catalog.
+func (e *Error) MessageKey() string {
+	return strings.ToLower(e.Code)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"final-project/models"
+	"final-project/utils"
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// BootstrapAdmin creates the first admin from ADMIN_EMAIL, ADMIN_PASSWORD
+// and ADMIN_NAME, since registered users can't be admins. It does nothing
+// when ADMIN_EMAIL is unset or an admin already exists.
+func BootstrapAdmin(db *gorm.DB) {
+	email := os.Getenv("ADMIN_EMAIL")
+	if email == "" {
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.ADMIN).Count(&count).Error; err != nil {
+		panic(err.Error())
+	}
+	if count > 0 {
+		return
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if len(password) < 8 || len(password) > 72 {
+		panic("ADMIN_PASSWORD must be 8 to 72 characters long")
+	}
+
+	admin := models.User{
+		Name:     utils.GetEnv("ADMIN_NAME", "Admin"),
+		Email:    email,
+		Password: password,
+		Role:     models.ADMIN,
+	}
+
+	// never promote an existing account, whoever registered it knows its
+	// password
+	if errs := admin.Validate(db); len(errs) > 0 {
+		panic("ADMIN_EMAIL belongs to an existing user")
+	}
+
+	if err := admin.BeforeSave(db, password); err != nil {
+		panic(err.Error())
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&admin).Error; err != nil {
+			return err
+		}
+
+		return models.RecordRoleChange(tx, nil, "", &admin, "")
+	})
+
+	if err != nil {
+		panic(err.Error())
+	}
+
+	fmt.Println("Admin", email, "is created")
+}
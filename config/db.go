@@ -56,6 +56,16 @@ func ConnectDB() *gorm.DB {
 		&models.ArticleCategory{},
 		&models.ArticleComment{},
 		&models.ReplyArticleComment{},
+		&models.Session{},
+		&models.ArticleRevision{},
+		&models.Upload{},
+		&models.ArticleSlug{},
+		&models.AuditLog{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.APIKey{},
 	)
+	migrate(db)
 	return db
 }
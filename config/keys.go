@@ -0,0 +1,72 @@
+package config
+
+import (
+	"final-project/utils"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// defaultSecret is the API_SECRET fallback, only fit for development.
+const defaultSecret = "secret"
+
+// LoadSigningKeys sets up the keys of access tokens. JWT_KEYS lists comma
+// separated kid=path pairs of PEM files, RSA or Ed25519 private keys, or
+// public keys of retired keys still verifying older tokens. JWT_ACTIVE_KEY
+// picks the key signing new tokens, the first one by default. To rotate,
+// add a key, make it active, and drop the old one once its tokens expired.
+// Without JWT_KEYS, tokens are signed with API_SECRET (HS256), and
+// JWT_ACCEPT_HS256 keeps accepting those while moving to JWT_KEYS.
+//
+// API_SECRET also derives the keys of action tokens and stored secrets, it
+// must not be the default in production.
+func LoadSigningKeys() {
+	environment := utils.GetEnv("ENVIRONMENT", "development")
+
+	// TOKEN_HOUR_LIFESPAN was the lifespan of access tokens before refresh
+	// tokens, it is ignored now
+	if os.Getenv("TOKEN_HOUR_LIFESPAN") != "" {
+		log.Println("TOKEN_HOUR_LIFESPAN is no longer used: access tokens last TOKEN_MINUTE_LIFESPAN minutes and refresh tokens REFRESH_TOKEN_HOUR_LIFESPAN hours")
+	}
+
+	if secret := utils.APISecret(); environment == "production" && (secret == "" || secret == defaultSecret) {
+		panic("API_SECRET must be set to a random value in production")
+	}
+
+	list := utils.GetEnv("JWT_KEYS", "")
+	if list == "" {
+		return
+	}
+
+	keys := []*utils.SigningKey{}
+	for _, pair := range strings.Split(list, ",") {
+		id, path, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || id == "" || path == "" {
+			panic(fmt.Sprintf("JWT_KEYS: expected kid=path, got '%v'", pair))
+		}
+
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			panic(err.Error())
+		}
+
+		key, err := utils.ParseSigningKey(id, pem)
+		if err != nil {
+			panic(err.Error())
+		}
+
+		keys = append(keys, key)
+	}
+
+	activeID := utils.GetEnv("JWT_ACTIVE_KEY", keys[0].ID)
+
+	ring, err := utils.NewKeyRing(keys, activeID, utils.GetEnv("JWT_ACCEPT_HS256", "false") == "true")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	utils.SetKeyRing(ring)
+
+	fmt.Println("Access tokens are signed with key", activeID)
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"final-project/mailer"
+	"final-project/utils"
+	"fmt"
+)
+
+// ConnectMailer picks the mailer from MAIL_DRIVER, "log" (default), which
+// only logs emails or writes them under MAIL_DIR, or "smtp".
+func ConnectMailer() mailer.Mailer {
+	driver := utils.GetEnv("MAIL_DRIVER", "log")
+
+	switch driver {
+	case "log":
+		return &mailer.Log{Dir: utils.GetEnv("MAIL_DIR", "")}
+	case "smtp":
+		return &mailer.SMTP{
+			Host:     utils.GetEnv("SMTP_HOST", "localhost"),
+			Port:     utils.GetEnv("SMTP_PORT", "587"),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     utils.GetEnv("MAIL_FROM", "no-reply@localhost"),
+		}
+	default:
+		panic(fmt.Sprintf("unknown MAIL_DRIVER '%v'", driver))
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "gorm.io/gorm"
+
+// migrations holds the schema changes AutoMigrate can't express. They run
+// on every start after AutoMigrate, so each statement must be idempotent.
+var migrations = []string{
+	// full-text search over articles, weighted title > description > content
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(content, '')), 'C')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING GIN (search_vector)`,
+	// "user" was the only non admin role before roles were introduced
+	`UPDATE users SET role = 'reader' WHERE role = 'user' OR role IS NULL OR role = ''`,
+}
+
+func migrate(db *gorm.DB) {
+	for _, migration := range migrations {
+		if err := db.Exec(migration).Error; err != nil {
+			panic(err.Error())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"final-project/oidc"
+	"final-project/utils"
+	"fmt"
+	"strings"
+)
+
+// ConnectOIDC configures the providers listed in OIDC_PROVIDERS, comma
+// separated names, each from OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES.
+func ConnectOIDC() map[string]oidc.Provider {
+	providers := map[string]oidc.Provider{}
+
+	for _, name := range strings.Split(utils.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		issuer := utils.GetEnv(prefix+"ISSUER", "")
+		clientID := utils.GetEnv(prefix+"CLIENT_ID", "")
+		if issuer == "" || clientID == "" {
+			panic(fmt.Sprintf("%vISSUER and %vCLIENT_ID are required", prefix, prefix))
+		}
+
+		providers[name] = &oidc.OpenID{
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: utils.GetEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(utils.GetEnv(prefix+"SCOPES", "")),
+		}
+	}
+
+	return providers
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"final-project/storage"
+	"final-project/utils"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ConnectStorage picks the upload storage from STORAGE_DRIVER, "local"
+// (default) or "s3". The local disk is ephemeral on Heroku, so production
+// deployments should use s3.
+func ConnectStorage() storage.Storage {
+	driver := utils.GetEnv("STORAGE_DRIVER", "local")
+
+	switch driver {
+	case "local":
+		return storage.NewLocal("public/upload", "/file/upload")
+	case "s3":
+		expiry, err := strconv.Atoi(utils.GetEnv("S3_URL_EXPIRY_MINUTES", "60"))
+		if err != nil {
+			panic(err.Error())
+		}
+
+		// S3 caps signed URLs at 7 days
+		if expiry <= 0 || expiry > 7*24*60 {
+			panic(fmt.Sprintf("S3_URL_EXPIRY_MINUTES must be between 1 and %v", 7*24*60))
+		}
+
+		return &storage.S3{
+			Endpoint:  utils.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    utils.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:    utils.GetEnv("S3_BUCKET", ""),
+			AccessKey: utils.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey: utils.GetEnv("S3_SECRET_KEY", ""),
+			PathStyle: utils.GetEnv("S3_USE_PATH_STYLE", "false") == "true",
+			PublicURL: utils.GetEnv("S3_PUBLIC_URL", ""),
+			Private:   utils.GetEnv("S3_PRIVATE", "false") == "true",
+			URLExpiry: time.Duration(expiry) * time.Minute,
+		}
+	default:
+		panic(fmt.Sprintf("unknown STORAGE_DRIVER '%v'", driver))
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"final-project/apperrors"
+	"final-project/models"
+	"final-project/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// Scopes are the permissions the key may use, e.g. article:write. A key
+	// without scopes can only read: editing the profile and posting or
+	// deleting comments need profile:write and comment:write, which every
+	// user has.
+	Scopes    []models.Permission `json:"scopes"`
+	ExpiresAt *time.Time          `json:"expires_at" binding:"omitempty,future"`
+}
+
+// CreatedAPIKey is a new API key with its secret, which is only shown once.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+var apiKeyListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "name", "created_at", "last_used_at"},
+	DefaultSort: "-id",
+	Filters: []utils.Filter{
+		{Param: "name", Column: "name", Like: true},
+	},
+}
+
+// Get API Keys godoc
+// @Summary     Get my API keys.
+// @Description The API keys of the current user, revoked and expired ones included.
+// @Tags        API Key
+// @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param name query string false "filter by name"
+// @Success     200 {object} []models.APIKey
+// @Router      /api-keys [get]
+// @Security ApiKeyAuth
+func GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	query, err := utils.ParseListQuery(c, apiKeyListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db.Where("user_id=?", currentUser(c).ID), &keys)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatePaginatedResponse(c, http.StatusOK, keys, meta)
+}
+
+// Create API Key godoc
+// @Summary     Create API key.
+// @Description Create an API key for machine clients, sent in the X-API-Key header instead of a token. It acts as the current user, limited to its scopes: without any it can only read, profile:write and comment:write let it edit the profile and post or delete comments. The key is only shown in this response.
+// @Tags        API Key
+// @Produce     json
+// @Param Body body APIKeyInput true "body for create API key"
+// @Success     201 {object} CreatedAPIKey
+// @Router      /api-keys [post]
+// @Security ApiKeyAuth
+func CreateAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := currentUser(c)
+
+	var input APIKeyInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	apiKey := models.APIKey{
+		UserID:    user.ID,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := apiKey.Validate(); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	// a key can't do more than its user
+	for _, scope := range apiKey.Scopes {
+		if !user.Can(scope) {
+			utils.CreateErrorResponse(c, apperrors.Forbidden("PERMISSION_DENIED", "permission", string(scope)))
+			return
+		}
+	}
+
+	key, err := apiKey.Generate()
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if err := db.Create(&apiKey).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusCreated, CreatedAPIKey{APIKey: apiKey, Key: key})
+}
+
+// Revoke API Key godoc
+// @Summary     Revoke API key.
+// @Tags        API Key
+// @Produce     json
+// @Param id path string true "API key id"
+// @Success     200 {object} bool
+// @Router      /api-keys/{id} [delete]
+// @Security ApiKeyAuth
+func RevokeAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var apiKey models.APIKey
+
+	if err := db.Where("id=? AND user_id=?", c.Param("id"), currentUser(c).ID).First(&apiKey).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		if err := apiKey.Revoke(db); err != nil {
+			utils.CreateErrorResponse(c, err)
+			return
+		}
+	}
+
+	utils.CreateResponse(c, http.StatusOK, true)
+}
@@ -1,9 +1,9 @@
 package controllers
 
 import (
+	"final-project/apperrors"
 	"final-project/models"
 	"final-project/utils"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -13,23 +13,61 @@ import (
 )
 
 type ArticleInput struct {
-	Title       string `json:"title"`
-	Content     string `json:"content"`
-	Description string `json:"description"`
-	ImageUrl    string `json:"image_url"`
-	Tags        string `json:"tag_ids"`
-	TagsNew     string `json:"tags"`
-	Categories  string `json:"category_ids"`
+	Title       string     `json:"title" binding:"required,max=100"`
+	Slug        string     `json:"slug" binding:"omitempty,slug"`
+	Content     string     `json:"content"`
+	Description string     `json:"description" binding:"max=255"`
+	ImageUrl    string     `json:"image_url" binding:"omitempty,httpurl,max=2048"`
+	Tags        string     `json:"tag_ids" binding:"omitempty,ids"`
+	TagsNew     string     `json:"tags"`
+	Categories  string     `json:"category_ids" binding:"omitempty,ids"`
+	PublishAt   *time.Time `json:"publish_at" binding:"omitempty,future"`
+}
+
+type ScheduleInput struct {
+	PublishAt time.Time `json:"publish_at" binding:"required,future"`
 }
 
 type CommentInput struct {
-	Content string `json:"content"`
+	Content string `json:"content" binding:"required"`
+}
+
+var articleListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "title", "created_at", "updated_at"},
+	DefaultSort: "-created_at",
+	Filters: []utils.Filter{
+		{Param: "title", Column: "title", Like: true},
+		{Param: "user_id", Column: "user_id"},
+		{Param: "is_published", Column: "is_published"},
+	},
+}
+
+var scheduledListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "publish_at"},
+	DefaultSort: "publish_at",
+}
+
+var articleSearchQuery = utils.QueryOptions{}
+
+var commentListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "created_at"},
+	DefaultSort: "created_at",
+	Filters: []utils.Filter{
+		{Param: "user_id", Column: "user_id"},
+	},
 }
 
 // Get All Articles godoc
 // @Summary     Get all articles.
 // @Tags        Article
 // @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param title query string false "filter by title"
+// @Param user_id query string false "filter by author id"
+// @Param is_published query bool false "filter by publish state"
 // @Success     200 {object} []models.Article
 // @Router      /articles [get]
 func GetArticles(c *gin.Context) {
@@ -37,23 +75,21 @@ func GetArticles(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Order("created_at DESC").Find(&articles).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	query, err := utils.ParseListQuery(c, articleListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if len(articles) > 0 {
-		_articles := []models.Article{}
-
-		for _, article := range articles {
-			article.GetDetails(db)
-			_articles = append(_articles, article)
-		}
+	meta, err := query.Find(db.Scopes(models.ArticleDetails, models.ArticleVisibleTo(currentUser(c))), &articles)
 
-		articles = _articles
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &articles)
+	utils.CreatePaginatedResponse(c, http.StatusOK, &articles, meta)
 }
 
 // Get Article godoc
@@ -67,32 +103,91 @@ func GetArticle(c *gin.Context) {
 	var article models.Article
 
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Where("id=?", c.Param("id")).First(&article).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := db.Scopes(models.ArticleDetails, models.ArticleVisibleTo(currentUser(c))).Where("id=?", c.Param("id")).First(&article).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	article.GetDetails(db)
 	utils.CreateResponse(c, http.StatusOK, &article)
 }
 
+// Search Articles godoc
+// @Summary     Search articles.
+// @Description Full-text search across title, description and content, ranked by relevance. Matches are highlighted with <mark> tags.
+// @Tags        Article
+// @Produce     json
+// @Param q query string true "search query, supports quoted phrases, 'or' and '-word'"
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Success     200 {object} []models.ArticleSearchResult
+// @Router      /articles/search [get]
+func SearchArticles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	q := strings.TrimSpace(c.Query("q"))
+
+	if q == "" {
+		utils.CreateErrorResponse(c, apperrors.BadRequest("SEARCH_QUERY_REQUIRED"))
+		return
+	}
+
+	query, err := utils.ParseListQuery(c, articleSearchQuery)
+
+	if err == nil && query.IsCursor {
+		err = apperrors.BadRequest("SEARCH_CURSOR_UNSUPPORTED")
+	}
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	results, total, err := models.SearchArticles(db, currentUser(c), q, query.PerPage, query.Offset())
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatePaginatedResponse(c, http.StatusOK, &results, query.Pagination(total))
+}
+
 // Get Article godoc
 // @Summary     Get article by slug.
 // @Tags        Article
 // @Produce     json
+// @Description Old slugs of an article redirect to its current slug.
 // @Param slug path string true "slug"
 // @Success     200 {object} models.Article
+// @Success     301
 // @Router      /articles/slug/{slug} [get]
 func GetArticleBySlug(c *gin.Context) {
 	var article models.Article
 
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Where("slug=?", c.Param("slug")).First(&article).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	err := db.Scopes(models.ArticleDetails, models.ArticleVisibleTo(currentUser(c))).Where("slug=?", c.Param("slug")).First(&article).Error
+
+	if err == nil {
+		utils.CreateResponse(c, http.StatusOK, article)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, article)
+	// an old slug of the article, send the client to the current one
+	err = db.Scopes(models.ArticleVisibleTo(currentUser(c))).
+		Where("articles.id = (?)", db.Model(&models.ArticleSlug{}).Select("article_id").Where("slug=?", c.Param("slug"))).
+		First(&article).Error
+
+	if err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	location := "/articles/slug/" + article.Slug
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+
+	c.Redirect(http.StatusMovedPermanently, location)
 }
 
 // Get Article godoc
@@ -100,6 +195,10 @@ func GetArticleBySlug(c *gin.Context) {
 // @Tags        Article
 // @Produce     json
 // @Param tag path string true "tag name"
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
 // @Success     200 {object} []models.Article
 // @Router      /articles/tag/{tag} [get]
 func GetArticleByTag(c *gin.Context) {
@@ -107,30 +206,28 @@ func GetArticleByTag(c *gin.Context) {
 	var tag models.Tag
 
 	if err := db.Where("name=?", c.Param("tag")).First(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	var articles []models.Article
-	var tags []models.ArticleTag
+	query, err := utils.ParseListQuery(c, articleListQuery)
 
-	if err := db.Where("tag_id=?", tag.ID).Find(&tags).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	for _, tag := range tags {
-		article := models.Article{}
-		err := db.Where("id=?", tag.ArticleID).First(&article).Error
+	var articles []models.Article
+	articleIDs := db.Model(&models.ArticleTag{}).Select("article_id").Where("tag_id=?", tag.ID)
 
-		article.GetDetails(db)
-		if err != nil {
-			return
-		}
-		articles = append(articles, article)
+	meta, err := query.Find(db.Scopes(models.ArticleDetails, models.ArticleVisibleTo(currentUser(c))).Where("id IN (?)", articleIDs), &articles)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &articles)
+	utils.CreatePaginatedResponse(c, http.StatusOK, &articles, meta)
 }
 
 // Get Article godoc
@@ -138,6 +235,10 @@ func GetArticleByTag(c *gin.Context) {
 // @Tags        Article
 // @Produce     json
 // @Param id path string true "category id"
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
 // @Success     200 {object} []models.Article
 // @Router      /articles/category/{id} [get]
 func GetArticleByCategory(c *gin.Context) {
@@ -145,34 +246,33 @@ func GetArticleByCategory(c *gin.Context) {
 	var category models.Category
 
 	if err := db.Where("id=?", c.Param("id")).First(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	var articles []models.Article
-	var categories []models.ArticleCategory
+	query, err := utils.ParseListQuery(c, articleListQuery)
 
-	if err := db.Where("category_id=?", category.ID).Find(&categories).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	for _, category := range categories {
-		article := models.Article{}
-		err := db.Where("id=?", category.ArticleID).First(&article).Error
+	var articles []models.Article
+	articleIDs := db.Model(&models.ArticleCategory{}).Select("article_id").Where("category_id=?", category.ID)
 
-		article.GetDetails(db)
-		if err != nil {
-			return
-		}
-		articles = append(articles, article)
+	meta, err := query.Find(db.Scopes(models.ArticleDetails, models.ArticleVisibleTo(currentUser(c))).Where("id IN (?)", articleIDs), &articles)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &articles)
+	utils.CreatePaginatedResponse(c, http.StatusOK, &articles, meta)
 }
 
 // Create Article godoc
 // @Summary     Create Article.
+// @Description Requires the article:write permission, and article:publish to set publish_at.
 // @Tags        Article
 // @Produce     json
 // @Param Body body ArticleInput true "body for create article (example ids input: '1,2,3')"
@@ -180,68 +280,91 @@ func GetArticleByCategory(c *gin.Context) {
 // @Router      /articles [post]
 // @Security ApiKeyAuth
 func CreateArticle(c *gin.Context) {
-	var errs = []string{}
 	var input ArticleInput
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	userID, err := utils.ExtractTokenID(c)
+	userID := currentUser(c).ID
 
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if input.PublishAt != nil && !currentUser(c).Can(models.PermArticlePublish) {
+		utils.CreateErrorResponse(c, errPublishDenied)
 		return
 	}
 
 	article := models.Article{
 		Title:       input.Title,
-		ImageUrl:    input.ImageUrl,
+		ImageUrl:    models.StoredFileURL(input.ImageUrl),
 		Content:     input.Content,
 		Description: input.Description,
+		PublishAt:   input.PublishAt,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	article.GetSlug(db)
+	article.GetSlug(db, input.Slug)
 	article.UserID = userID
 
 	if err := article.Validate(db); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := db.Create(&article).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	categories := strings.Split(input.Categories, ",")
+	tags := strings.Split(input.Tags, ",")
+
+	category_ids, err := utils.SliceStringToUInt(categories)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	categories := strings.Split(input.Categories, ",")
-	tags := strings.Split(input.Tags, ",")
+	tag_ids, err := utils.SliceStringToUInt(tags)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&article).Error; err != nil {
+			return err
+		}
+
+		if err := article.InsertCategories(tx, category_ids); err != nil {
+			return err
+		}
 
-	category_ids := utils.SliceStringToUInt(categories)
-	tag_ids := utils.SliceStringToUInt(tags)
+		if err := article.InsertTags(tx, tag_ids, strings.Split(input.TagsNew, ",")); err != nil {
+			return err
+		}
 
-	errs = append(errs, article.InsertCategories(db, category_ids)...)
-	errs = append(errs, article.InsertTags(db, tag_ids, strings.Split(input.TagsNew, ","))...)
+		if err := article.GetDetails(tx); err != nil {
+			return err
+		}
 
-	if len(errs) > 0 {
-		if err := article.Delete(db); err != nil {
-			fmt.Println(err.Error())
+		if err := article.SyncImages(tx); err != nil {
+			return err
 		}
-		utils.CreateResponse(c, http.StatusBadRequest, errs)
+
+		_, err := article.SaveRevision(tx, userID)
+		return err
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	article.GetDetails(db)
 	utils.CreateResponse(c, http.StatusCreated, &article)
 }
 
 // Update Article godoc
 // @Summary     Update Article.
+// @Description Authors can only update their own articles, editors and admins any. Setting publish_at requires the article:publish permission, leaving it out clears the schedule for users who have it. Without a slug, the slug is kept unless the title changes.
 // @Tags        Article
 // @Produce     json
 // @Param id path string true "article id"
@@ -253,68 +376,115 @@ func UpdateArticle(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var input ArticleInput
 	article := models.Article{}
-	details := models.Article{}
 
-	if err := db.Where("id=?", c.Param("id")).First(&article).First(&details).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	details.GetDetails(db)
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	var errs = []string{}
+	if input.PublishAt != nil && !currentUser(c).Can(models.PermArticlePublish) {
+		utils.CreateErrorResponse(c, errPublishDenied)
+		return
+	}
 
 	updated := models.Article{
+		ID:          article.ID,
 		Title:       input.Title,
 		Content:     input.Content,
 		Description: input.Description,
-		ImageUrl:    input.ImageUrl,
+		ImageUrl:    models.StoredFileURL(input.ImageUrl),
+		PublishAt:   input.PublishAt,
 		UpdatedAt:   time.Now(),
 	}
 
-	updated.GetSlug(db)
+	// only publishers may set or clear the schedule
+	if !currentUser(c).Can(models.PermArticlePublish) {
+		updated.PublishAt = article.PublishAt
+	}
+
+	// without a new slug, the slug only follows a new title, a custom slug
+	// is kept
+	updated.Slug = article.Slug
+	if input.Slug != "" || input.Title != article.Title {
+		updated.GetSlug(db, input.Slug)
+	}
 
 	if err := updated.Validate(db); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := article.BeforeUpdate(db); err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	categories := strings.Split(input.Categories, ",")
+	tags := strings.Split(input.Tags, ",")
+
+	category_ids, err := utils.SliceStringToUInt(categories)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := db.Model(&article).Updates(updated).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	tag_ids, err := utils.SliceStringToUInt(tags)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	categories := strings.Split(input.Categories, ",")
-	tags := strings.Split(input.Tags, ",")
+	oldSlug := article.Slug
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Select, so the content, description, image and schedule can be
+		// cleared
+		err := tx.Model(&article).
+			Select("title", "slug", "content", "description", "image_url", "publish_at", "updated_at").
+			Updates(&updated).Error
+		if err != nil {
+			return err
+		}
+
+		if err := article.ClearRelations(tx); err != nil {
+			return err
+		}
+
+		if err := article.InsertCategories(tx, category_ids); err != nil {
+			return err
+		}
 
-	category_ids := utils.SliceStringToUInt(categories)
-	tag_ids := utils.SliceStringToUInt(tags)
+		if err := article.InsertTags(tx, tag_ids, strings.Split(input.TagsNew, ",")); err != nil {
+			return err
+		}
+
+		if err := article.GetDetails(tx); err != nil {
+			return err
+		}
+
+		if err := article.SyncImages(tx); err != nil {
+			return err
+		}
+
+		if err := article.RecordSlugChange(tx, oldSlug); err != nil {
+			return err
+		}
 
-	errs = append(errs, article.InsertCategories(db, category_ids)...)
-	errs = append(errs, article.InsertTags(db, tag_ids, strings.Split(input.TagsNew, ","))...)
+		_, err = article.SaveRevision(tx, currentUser(c).ID)
+		return err
+	})
 
-	if len(errs) > 0 {
-		article.RestoreUpdate(db, &details)
-		utils.CreateResponse(c, http.StatusBadRequest, errs)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	article.GetDetails(db)
 	utils.CreateResponse(c, http.StatusOK, article)
 }
 
 // Delete Article godoc
 // @Summary     Delete article.
+// @Description Authors can only delete their own articles, editors and admins any.
 // @Tags        Article
 // @Produce     json
 // @Param id path string true "article id"
@@ -325,13 +495,13 @@ func DeleteArticle(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var article models.Article
 
-	if err := db.Where("id=?", c.Param("id")).First(&article).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := article.Delete(db); err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := db.Transaction(article.Delete); err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -340,100 +510,153 @@ func DeleteArticle(c *gin.Context) {
 
 // Publish Article godoc
 // @Summary     Publish Article.
+// @Description Requires the article:publish permission.
 // @Tags        Article
 // @Produce     json
 // @Param 		id path string true "article id"
-// @Success     200 {object} models.User
+// @Success     200 {object} models.Article
 // @Router      /articles/publish/{id} [patch]
 // @Security ApiKeyAuth
 func PublishArticle(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var article models.Article
-	var details models.Article
 
-	if err := db.Where("id=?", c.Param("id")).First(&article).First(&details).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	details.GetDetails(db)
-
-	category_ids := []uint{}
-	for _, c := range details.Categories {
-		category_ids = append(category_ids, c.CategoryID)
+	if err := db.Model(&article).Updates(map[string]interface{}{"is_published": true, "publish_at": nil}).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	tag_ids := []uint{}
-	for _, t := range details.Tags {
-		tag_ids = append(tag_ids, t.TagID)
+	utils.CreateResponse(c, http.StatusOK, &article)
+}
+
+// Unpublish Article godoc
+// @Summary     Unpublish Article.
+// @Description Requires the article:publish permission.
+// @Tags        Article
+// @Produce     json
+// @Param 		id path string true "article id"
+// @Success     200 {object} models.Article
+// @Router      /articles/unpublish/{id} [patch]
+// @Security ApiKeyAuth
+func UnpublishArticle(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var article models.Article
+
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	if err := db.Model(&article).Update("is_published", true).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := db.Model(&article).Updates(map[string]interface{}{"is_published": false, "publish_at": nil}).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	var errs = []string{}
+	utils.CreateResponse(c, http.StatusOK, &article)
+}
 
-	errs = append(errs, article.InsertCategories(db, category_ids)...)
-	errs = append(errs, article.InsertTags(db, tag_ids, []string{})...)
+// Get Scheduled Articles godoc
+// @Summary     Get scheduled articles.
+// @Description Drafts waiting for the publisher worker, soonest first.
+// @Tags        Article
+// @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Success     200 {object} []models.Article
+// @Router      /articles/scheduled [get]
+// @Security ApiKeyAuth
+func GetScheduledArticles(c *gin.Context) {
+	var articles []models.Article
 
-	if len(errs) > 0 {
-		if err := article.Delete(db); err != nil {
-			fmt.Println(err.Error())
-		}
-		utils.CreateResponse(c, http.StatusBadRequest, errs)
+	db := c.MustGet("db").(*gorm.DB)
+
+	query, err := utils.ParseListQuery(c, scheduledListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &article)
+	meta, err := query.Find(db.Scopes(models.ArticleDetails).Where("is_published = ? AND publish_at IS NOT NULL", false), &articles)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatePaginatedResponse(c, http.StatusOK, &articles, meta)
 }
 
-// Unpublish Article godoc
-// @Summary     Unpublish Article.
+// Schedule Article godoc
+// @Summary     Schedule Article.
+// @Description Publish the article automatically at publish_at.
 // @Tags        Article
 // @Produce     json
 // @Param 		id path string true "article id"
-// @Success     200 {object} models.User
-// @Router      /articles/unpublish/{id} [patch]
+// @Param Body body ScheduleInput true "body for schedule article (RFC 3339 time)"
+// @Success     200 {object} models.Article
+// @Router      /articles/schedule/{id} [patch]
 // @Security ApiKeyAuth
-func UnpublishArticle(c *gin.Context) {
+func ScheduleArticle(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var article models.Article
-	var details models.Article
+	var input ScheduleInput
 
-	if err := db.Where("id=?", c.Param("id")).First(&article).First(&details).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	details.GetDetails(db)
-
-	category_ids := []uint{}
-	for _, c := range details.Categories {
-		category_ids = append(category_ids, c.CategoryID)
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	tag_ids := []uint{}
-	for _, t := range details.Tags {
-		tag_ids = append(tag_ids, t.TagID)
+	if article.IsPublished {
+		utils.CreateErrorResponse(c, apperrors.Conflict("ARTICLE_ALREADY_PUBLISHED"))
+		return
 	}
 
-	if err := db.Model(&article).Update("is_published", false).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	article.PublishAt = &input.PublishAt
+
+	if err := db.Model(&article).UpdateColumns(map[string]interface{}{"publish_at": input.PublishAt, "updated_at": time.Now()}).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	var errs = []string{}
+	utils.CreateResponse(c, http.StatusOK, &article)
+}
 
-	errs = append(errs, article.InsertCategories(db, category_ids)...)
-	errs = append(errs, article.InsertTags(db, tag_ids, []string{})...)
+// Cancel Scheduled Article godoc
+// @Summary     Cancel scheduled publication.
+// @Tags        Article
+// @Produce     json
+// @Param 		id path string true "article id"
+// @Success     200 {object} models.Article
+// @Router      /articles/schedule/{id} [delete]
+// @Security ApiKeyAuth
+func CancelScheduledArticle(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var article models.Article
 
-	if len(errs) > 0 {
-		if err := article.Delete(db); err != nil {
-			fmt.Println(err.Error())
-		}
-		utils.CreateResponse(c, http.StatusBadRequest, errs)
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if article.PublishAt == nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	if err := db.Model(&article).UpdateColumns(map[string]interface{}{"publish_at": nil, "updated_at": time.Now()}).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -445,30 +668,39 @@ func UnpublishArticle(c *gin.Context) {
 // @Tags        Article Comment
 // @Produce     json
 // @Param id path string true "article id"
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param user_id query string false "filter by commenter id"
 // @Success     200 {object} []models.ArticleComment
 // @Router      /articles/{id}/comments [get]
 func GetComments(c *gin.Context) {
 	var comments []models.ArticleComment
+	var article models.Article
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Where("article_id=? AND is_reply=false", c.Param("id")).Find(&comments).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := db.Scopes(models.ArticleVisibleTo(currentUser(c))).Where("id=?", c.Param("id")).First(&article).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	_comments := []models.ArticleComment{}
+	query, err := utils.ParseListQuery(c, commentListQuery)
 
-	for _, comment := range comments {
-		if err := comment.GetDetails(db); err != nil {
-			utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db.Preload("User").Where("article_id=? AND is_reply=false", article.ID), &comments)
 
-		_comments = append(_comments, comment)
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &_comments)
+	utils.CreatePaginatedResponse(c, http.StatusOK, &comments, meta)
 }
 
 // Create Comment godoc
@@ -484,22 +716,17 @@ func CreateComment(c *gin.Context) {
 	var input CommentInput
 	var article models.Article
 
-	userID, err := utils.ExtractTokenID(c)
+	userID := currentUser(c).ID
 
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Where("id=?", c.Param("id")).First(&article).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := db.Scopes(models.ArticleVisibleTo(currentUser(c))).Where("id=?", c.Param("id")).First(&article).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
@@ -513,7 +740,7 @@ func CreateComment(c *gin.Context) {
 	}
 
 	if err := db.Create(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -522,6 +749,7 @@ func CreateComment(c *gin.Context) {
 
 // Delete Comment godoc
 // @Summary     Delete Comment.
+// @Description Only the author of the comment and users with the comment:moderate permission can delete it.
 // @Tags        Article Comment
 // @Produce     json
 // @Param id path string true "comment id"
@@ -532,25 +760,20 @@ func DeleteComment(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var comment models.ArticleComment
 
-	userID, err := utils.ExtractTokenID(c)
-
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
+	userID := currentUser(c).ID
 
 	if err := db.Where("id=?", c.Param("id")).First(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	if userID != comment.UserID {
-		utils.CreateResponse(c, http.StatusBadRequest, "hanya pembuat komentar yang dapat menghapus komentar")
+	if userID != comment.UserID && !currentUser(c).Can(models.PermCommentModerate) {
+		utils.CreateErrorResponse(c, apperrors.Forbidden("COMMENT_AUTHOR_ONLY"))
 		return
 	}
 
 	if err := db.Delete(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -566,31 +789,21 @@ func DeleteComment(c *gin.Context) {
 // @Router      /articles/comments/{id}/replies [get]
 func GetReplyComments(c *gin.Context) {
 	var comments []models.ReplyArticleComment
+	var parent models.ArticleComment
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Where("parent_id=?", c.Param("id")).Find(&comments).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := db.Preload("Article").Where("id=?", c.Param("id")).First(&parent).Error; err != nil || !parent.Article.IsVisibleTo(currentUser(c)) {
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	_comments := []models.ReplyArticleComment{}
-
-	for _, comment := range comments {
-		if err := comment.GetDetails(db); err != nil {
-			utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		if err := comment.GetParent(db); err != nil {
-			utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		_comments = append(_comments, comment)
+	if err := db.Scopes(models.ReplyDetails).Where("parent_id=?", parent.ID).Find(&comments).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &_comments)
+	utils.CreateResponse(c, http.StatusOK, &comments)
 }
 
 // Create Reply Comment godoc
@@ -606,22 +819,17 @@ func CreateReplyComment(c *gin.Context) {
 	var input CommentInput
 	var parent models.ArticleComment
 
-	userID, err := utils.ExtractTokenID(c)
-
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
+	userID := currentUser(c).ID
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Where("id=?", c.Param("id")).First(&parent).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+	if err := db.Preload("Article").Where("id=?", c.Param("id")).First(&parent).Error; err != nil || !parent.Article.IsVisibleTo(currentUser(c)) {
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
@@ -635,7 +843,7 @@ func CreateReplyComment(c *gin.Context) {
 	}
 
 	if err := db.Create(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -650,7 +858,7 @@ func CreateReplyComment(c *gin.Context) {
 	}
 
 	if err := db.Create(&replyComment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -659,6 +867,7 @@ func CreateReplyComment(c *gin.Context) {
 
 // Delete Reply Comment godoc
 // @Summary     Delete Reply Comment.
+// @Description Only the author of the reply and users with the comment:moderate permission can delete it.
 // @Tags        Article Comment
 // @Produce     json
 // @Param id path string true "reply comment id"
@@ -670,35 +879,30 @@ func DeleteReplyComment(c *gin.Context) {
 	var replyComment models.ReplyArticleComment
 	var comment models.ArticleComment
 
-	userID, err := utils.ExtractTokenID(c)
-
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
+	userID := currentUser(c).ID
 
 	if err := db.Where("id=?", c.Param("id")).First(&replyComment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	if err := db.Where("id=?", replyComment.CommentID).First(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	if userID != comment.UserID && userID != replyComment.UserID {
-		utils.CreateResponse(c, http.StatusBadRequest, "hanya pembuat komentar yang dapat menghapus komentar")
+	if userID != comment.UserID && userID != replyComment.UserID && !currentUser(c).Can(models.PermCommentModerate) {
+		utils.CreateErrorResponse(c, apperrors.Forbidden("COMMENT_AUTHOR_ONLY"))
 		return
 	}
 
 	if err := db.Delete(&comment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Delete(&replyComment).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"final-project/models"
+	"final-project/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the database of TEST_DATABASE_DSN, which the tests
+// write to, so it must not hold real data. Tests needing it are skipped
+// without it.
+func testDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Category{},
+		&models.Tag{},
+		&models.Article{},
+		&models.ArticleTag{},
+		&models.ArticleCategory{},
+		&models.ArticleRevision{},
+		&models.Upload{},
+		&models.ArticleSlug{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// articleFixture is an author with a category and a tag, removed with
+// everything linked to them when the test ends.
+type articleFixture struct {
+	db       *gorm.DB
+	user     models.User
+	category models.Category
+	tag      models.Tag
+}
+
+func newArticleFixture(t *testing.T) *articleFixture {
+	db := testDB(t)
+	suffix := fmt.Sprint(time.Now().UnixNano())
+
+	f := &articleFixture{
+		db:       db,
+		user:     models.User{Name: "author", Email: "author-" + suffix + "@example.com", Password: "-", Role: models.AUTHOR},
+		category: models.Category{Name: "category-" + suffix},
+		tag:      models.Tag{Name: "tag-" + suffix},
+	}
+
+	for _, row := range []interface{}{&f.user, &f.category, &f.tag} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Cleanup(func() {
+		var ids []uint
+		db.Model(&models.Article{}).Where("user_id = ?", f.user.ID).Pluck("id", &ids)
+		for _, id := range ids {
+			article := models.Article{ID: id}
+			article.Delete(db)
+		}
+		db.Delete(&f.tag)
+		db.Delete(&f.category)
+		db.Delete(&f.user)
+	})
+
+	return f
+}
+
+// serve calls handler as the fixture's author, with body as JSON.
+func (f *articleFixture) serve(handler gin.HandlerFunc, params gin.Params, body gin.H) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("db", f.db)
+	c.Set("user", f.user)
+
+	handler(c)
+
+	return w
+}
+
+func (f *articleFixture) count(t *testing.T, model interface{}, query string, args ...interface{}) int64 {
+	var n int64
+	if err := f.db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCreateArticleRollsBack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newArticleFixture(t)
+
+	tests := []struct {
+		name string
+		body gin.H
+	}{
+		// the article is inserted before the categories are checked
+		{"unknown category", gin.H{"category_ids": "999999999", "tag_ids": fmt.Sprint(f.tag.ID)}},
+		// and the categories before the tags
+		{"unknown tag", gin.H{"category_ids": fmt.Sprint(f.category.ID), "tag_ids": "999999999", "tags": "new-" + f.tag.Name}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title := fmt.Sprintf("rollback %v %v", i, f.tag.Name)
+			tt.body["title"] = title
+			tt.body["content"] = "content"
+
+			w := f.serve(CreateArticle, nil, tt.body)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %v, want %v: %v", w.Code, http.StatusUnprocessableEntity, w.Body)
+			}
+
+			if n := f.count(t, &models.Article{}, "title = ?", title); n != 0 {
+				t.Errorf("%v articles remain", n)
+			}
+			if n := f.count(t, &models.ArticleCategory{}, "category_id = ?", f.category.ID); n != 0 {
+				t.Errorf("%v article categories remain", n)
+			}
+			if n := f.count(t, &models.ArticleTag{}, "tag_id = ?", f.tag.ID); n != 0 {
+				t.Errorf("%v article tags remain", n)
+			}
+			if n := f.count(t, &models.Tag{}, "name = ?", "new-"+f.tag.Name); n != 0 {
+				t.Errorf("%v new tags remain", n)
+			}
+			if n := f.count(t, &models.ArticleRevision{}, "title = ?", title); n != 0 {
+				t.Errorf("%v revisions remain", n)
+			}
+		})
+	}
+}
+
+func TestUpdateArticleRollsBack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newArticleFixture(t)
+
+	title := "rollback update " + f.tag.Name
+	w := f.serve(CreateArticle, nil, gin.H{
+		"title":        title,
+		"content":      "content",
+		"category_ids": fmt.Sprint(f.category.ID),
+		"tag_ids":      fmt.Sprint(f.tag.ID),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %v: %v", w.Code, w.Body)
+	}
+
+	article := models.Article{}
+	if err := f.db.Where("title = ?", title).Take(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the relations are cleared before the unknown tag is found
+	w = f.serve(UpdateArticle, gin.Params{{Key: "id", Value: fmt.Sprint(article.ID)}}, gin.H{
+		"title":        title + " updated",
+		"content":      "updated",
+		"category_ids": fmt.Sprint(f.category.ID),
+		"tag_ids":      "999999999",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("update status = %v, want %v: %v", w.Code, http.StatusUnprocessableEntity, w.Body)
+	}
+
+	if n := f.count(t, &models.Article{}, "id = ? AND title = ? AND content = ?", article.ID, title, "content"); n != 1 {
+		t.Errorf("article was changed")
+	}
+	if n := f.count(t, &models.ArticleCategory{}, "article_id = ? AND category_id = ?", article.ID, f.category.ID); n != 1 {
+		t.Errorf("%v article categories, want 1", n)
+	}
+	if n := f.count(t, &models.ArticleTag{}, "article_id = ? AND tag_id = ?", article.ID, f.tag.ID); n != 1 {
+		t.Errorf("%v article tags, want 1", n)
+	}
+	if n := f.count(t, &models.ArticleRevision{}, "article_id = ?", article.ID); n != 1 {
+		t.Errorf("%v revisions, want 1", n)
+	}
+}
+
+func TestUpdateArticleKeepsSlug(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newArticleFixture(t)
+
+	title := "keep slug " + f.tag.Name
+	custom := "custom-" + f.tag.Name
+
+	w := f.serve(CreateArticle, nil, gin.H{"title": title, "slug": custom, "content": "content"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %v: %v", w.Code, w.Body)
+	}
+
+	article := models.Article{}
+	if err := f.db.Where("title = ?", title).Take(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "id", Value: fmt.Sprint(article.ID)}}
+
+	// same title, no slug: the custom slug stays
+	w = f.serve(UpdateArticle, params, gin.H{"title": title, "content": "updated"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %v: %v", w.Code, w.Body)
+	}
+
+	f.db.First(&article, article.ID)
+	if article.Slug != custom {
+		t.Errorf("slug = %v, want %v", article.Slug, custom)
+	}
+	if n := f.count(t, &models.ArticleSlug{}, "article_id = ?", article.ID); n != 0 {
+		t.Errorf("%v old slugs recorded, want 0", n)
+	}
+
+	// a new title moves the slug, the old one redirects
+	w = f.serve(UpdateArticle, params, gin.H{"title": title + " renamed", "content": "updated"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("rename status = %v: %v", w.Code, w.Body)
+	}
+
+	f.db.First(&article, article.ID)
+	if want := utils.Slugify(title + " renamed"); article.Slug != want {
+		t.Errorf("slug = %v, want %v", article.Slug, want)
+	}
+	if n := f.count(t, &models.ArticleSlug{}, "article_id = ? AND slug = ?", article.ID, custom); n != 1 {
+		t.Errorf("old slug recorded %v times, want 1", n)
+	}
+}
+
+func TestUpdateArticleClearsFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newArticleFixture(t)
+
+	title := "clear fields " + f.tag.Name
+	w := f.serve(CreateArticle, nil, gin.H{
+		"title":       title,
+		"content":     "content",
+		"description": "description",
+		"image_url":   "https://example.com/image.png",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %v: %v", w.Code, w.Body)
+	}
+
+	article := models.Article{}
+	if err := f.db.Where("title = ?", title).Take(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w = f.serve(UpdateArticle, gin.Params{{Key: "id", Value: fmt.Sprint(article.ID)}}, gin.H{"title": title})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %v: %v", w.Code, w.Body)
+	}
+
+	f.db.First(&article, article.ID)
+	if article.Content != "" || article.Description != "" || article.ImageUrl != "" {
+		t.Errorf("fields not cleared: content %q, description %q, image %q", article.Content, article.Description, article.ImageUrl)
+	}
+
+	revision := models.ArticleRevision{}
+	f.db.Where("article_id = ?", article.ID).Order("revision DESC").First(&revision)
+	if revision.Content != article.Content || revision.ImageUrl != article.ImageUrl {
+		t.Errorf("revision %+v doesn't match the article", revision)
+	}
+}
+
+func TestArticleTitleTaken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newArticleFixture(t)
+
+	title := "taken " + f.tag.Name
+	for _, name := range []string{title, title + " other"} {
+		if w := f.serve(CreateArticle, nil, gin.H{"title": name, "content": "content"}); w.Code != http.StatusCreated {
+			t.Fatalf("create status = %v: %v", w.Code, w.Body)
+		}
+	}
+
+	// a client mistake, not the unique index failing
+	w := f.serve(CreateArticle, nil, gin.H{"title": title, "content": "content"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create status = %v, want %v: %v", w.Code, http.StatusUnprocessableEntity, w.Body)
+	}
+
+	other := models.Article{}
+	if err := f.db.Where("title = ?", title+" other").Take(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w = f.serve(UpdateArticle, gin.Params{{Key: "id", Value: fmt.Sprint(other.ID)}}, gin.H{"title": title, "content": "content"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("update status = %v, want %v: %v", w.Code, http.StatusUnprocessableEntity, w.Body)
+	}
+
+	// keeping its own title is fine
+	w = f.serve(UpdateArticle, gin.Params{{Key: "id", Value: fmt.Sprint(other.ID)}}, gin.H{"title": other.Title, "content": "updated"})
+	if w.Code != http.StatusOK {
+		t.Errorf("update own title status = %v: %v", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"final-project/apperrors"
+	"final-project/models"
+	"final-project/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var revisionListQuery = utils.QueryOptions{
+	Sorts:       []string{"revision", "created_at"},
+	DefaultSort: "-revision",
+	Filters: []utils.Filter{
+		{Param: "user_id", Column: "user_id"},
+	},
+}
+
+type RevisionDiff struct {
+	From    uint                    `json:"from"`
+	To      uint                    `json:"to"`
+	Changes []models.RevisionChange `json:"changes"`
+}
+
+// Get Article Revisions godoc
+// @Summary     Get article revisions.
+// @Tags        Article Revision
+// @Produce     json
+// @Param id path string true "article id"
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param user_id query string false "filter by editor id"
+// @Success     200 {object} []models.ArticleRevision
+// @Router      /articles/{id}/revisions [get]
+// @Security ApiKeyAuth
+func GetArticleRevisions(c *gin.Context) {
+	var article models.Article
+	var revisions []models.ArticleRevision
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	query, err := utils.ParseListQuery(c, revisionListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db.Preload("User").Where("article_id=?", article.ID), &revisions)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatePaginatedResponse(c, http.StatusOK, &revisions, meta)
+}
+
+// Diff Article Revisions godoc
+// @Summary     Diff two article revisions.
+// @Tags        Article Revision
+// @Produce     json
+// @Param id path string true "article id"
+// @Param from query int true "revision number to diff from"
+// @Param to query int true "revision number to diff to"
+// @Success     200 {object} RevisionDiff
+// @Router      /articles/{id}/revisions/diff [get]
+// @Security ApiKeyAuth
+func DiffArticleRevisions(c *gin.Context) {
+	var article models.Article
+	var from models.ArticleRevision
+	var to models.ArticleRevision
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if err := db.Where("article_id=? AND revision=?", article.ID, c.Query("from")).First(&from).Error; err != nil {
+		utils.CreateErrorResponse(c, apperrors.NotFound("REVISION_NOT_FOUND", "revision", "from"))
+		return
+	}
+
+	if err := db.Where("article_id=? AND revision=?", article.ID, c.Query("to")).First(&to).Error; err != nil {
+		utils.CreateErrorResponse(c, apperrors.NotFound("REVISION_NOT_FOUND", "revision", "to"))
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, RevisionDiff{
+		From:    from.Revision,
+		To:      to.Revision,
+		Changes: from.Diff(&to),
+	})
+}
+
+// Restore Article Revision godoc
+// @Summary     Restore article revision.
+// @Description Bring the article back to the given revision. The restore is saved as a new revision.
+// @Tags        Article Revision
+// @Produce     json
+// @Param id path string true "article id"
+// @Param rev path int true "revision number"
+// @Success     200 {object} models.Article
+// @Router      /articles/{id}/revisions/{rev}/restore [post]
+// @Security ApiKeyAuth
+func RestoreArticleRevision(c *gin.Context) {
+	var article models.Article
+	var revision models.ArticleRevision
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	if err := findEditableArticle(c, db, &article); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if err := db.Where("article_id=? AND revision=?", article.ID, c.Param("rev")).First(&revision).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	restored := models.Article{
+		ID:          article.ID,
+		Title:       revision.Title,
+		Slug:        models.UniqueArticleSlug(db, revision.Slug, article.ID),
+		ImageUrl:    revision.ImageUrl,
+		Content:     revision.Content,
+		Description: revision.Description,
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := restored.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	category_ids, err := utils.SliceStringToUInt(strings.Split(revision.CategoryIDs, ","))
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	tag_ids, err := utils.SliceStringToUInt(strings.Split(revision.TagIDs, ","))
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	oldSlug := article.Slug
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Select, so empty fields of the revision are restored too
+		err := tx.Model(&article).
+			Select("title", "slug", "content", "description", "image_url", "updated_at").
+			Updates(&restored).Error
+		if err != nil {
+			return err
+		}
+
+		if err := article.ClearRelations(tx); err != nil {
+			return err
+		}
+
+		if err := article.InsertCategories(tx, category_ids); err != nil {
+			return err
+		}
+
+		if err := article.InsertTags(tx, tag_ids, []string{}); err != nil {
+			return err
+		}
+
+		if err := article.GetDetails(tx); err != nil {
+			return err
+		}
+
+		if err := article.SyncImages(tx); err != nil {
+			return err
+		}
+
+		if err := article.RecordSlugChange(tx, oldSlug); err != nil {
+			return err
+		}
+
+		_, err = article.SaveRevision(tx, currentUser(c).ID)
+		return err
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, article)
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"final-project/models"
+	"final-project/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var auditLogListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "created_at"},
+	DefaultSort: "-id",
+	Filters: []utils.Filter{
+		{Param: "action", Column: "action"},
+		{Param: "actor_id", Column: "actor_id"},
+		{Param: "target_type", Column: "target_type"},
+		{Param: "target_id", Column: "target_id"},
+	},
+}
+
+// Get Audit Logs godoc
+// @Summary     Get audit logs.
+// @Description Sensitive actions, such as role changes, newest first.
+// @Tags        Audit Log
+// @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param action query string false "filter by action, e.g. user.role_changed"
+// @Param actor_id query string false "filter by actor id"
+// @Param target_type query string false "filter by target type"
+// @Param target_id query string false "filter by target id"
+// @Success     200 {object} []models.AuditLog
+// @Router      /audit-logs [get]
+// @Security ApiKeyAuth
+func GetAuditLogs(c *gin.Context) {
+	var logs []models.AuditLog
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	query, err := utils.ParseListQuery(c, auditLogListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db, &logs)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatePaginatedResponse(c, http.StatusOK, logs, meta)
+}
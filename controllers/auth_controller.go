@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"final-project/apperrors"
+	"final-project/mailer"
+	"final-project/models"
+	"final-project/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// Verify Email godoc
+// @Summary     Verify email.
+// @Description Confirm the email of the user the token was sent to. The link in the verification email points here.
+// @Tags        Auth
+// @Produce     json
+// @Param token query string true "verification token"
+// @Success     200 {object} string
+// @Router      /auth/verify [get]
+func VerifyEmail(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	user, err := models.FindUserByActionToken(db, c.Query("token"), utils.PurposeVerifyEmail)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := user.MarkEmailVerified(db); err != nil {
+			utils.CreateErrorResponse(c, err)
+			return
+		}
+	}
+
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("email_verified"))
+}
+
+// Resend Verification Email godoc
+// @Summary     Resend verification email.
+// @Description The email links to BASE_URL, it is not sent while BASE_URL is unset.
+// @Tags        Auth
+// @Produce     json
+// @Success     200 {object} string
+// @Router      /auth/verify/resend [post]
+// @Security ApiKeyAuth
+func ResendVerificationEmail(c *gin.Context) {
+	user := currentUser(c)
+
+	if user.EmailVerifiedAt != nil {
+		utils.CreateErrorResponse(c, apperrors.Conflict("EMAIL_ALREADY_VERIFIED"))
+		return
+	}
+
+	sendVerificationEmail(c, user)
+
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("verification_sent"))
+}
+
+// JWKS godoc
+// @Summary     Token verification keys.
+// @Description The public keys access tokens are signed with, as a JSON Web Key Set, for other services to verify them. Tokens name their key in the kid header. The set is empty while tokens are signed with API_SECRET.
+// @Tags        Auth
+// @Produce     json
+// @Success     200 {object} map[string][]utils.JSONWebKey
+// @Router      /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	// the set is served as is, JWKS clients don't expect our envelope
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JSONWebKeys()})
+}
+
+// Forgot Password godoc
+// @Summary     Request a password reset.
+// @Description Email a password reset link to the user. The response is the same whether the email is registered or not.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body ForgotPasswordInput true "the email of the account"
+// @Success     200 {object} string
+// @Router      /auth/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var input ForgotPasswordInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	user := models.User{}
+
+	err := db.Where("email=?", input.Email).Take(&user).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if err == nil {
+		sendPasswordResetEmail(c, &user)
+	}
+
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("password_reset_sent"))
+}
+
+// Reset Password godoc
+// @Summary     Reset password.
+// @Description Set a new password with the token of a password reset email. Every session of the user is revoked.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body ResetPasswordInput true "the reset token and the new password"
+// @Success     200 {object} string
+// @Router      /auth/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var input ResetPasswordInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	user, err := models.FindUserByActionToken(db, input.Token, utils.PurposeResetPassword)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error { return user.ResetPassword(tx, input.NewPassword) }); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("password_reset"))
+}
+
+// sendVerificationEmail mails user a link to VerifyEmail, in the language of
+// the request. The link is based on BASE_URL, never on the Host header of
+// the request, which would let the sender point it to their own site and
+// collect the token. Without BASE_URL no email is sent.
+func sendVerificationEmail(c *gin.Context, user *models.User) {
+	base := utils.GetEnv("BASE_URL", "")
+	if base == "" {
+		log.Printf("verification email for user %v not sent: BASE_URL is not set", user.ID)
+		return
+	}
+
+	token, err := user.VerificationToken()
+	if err != nil {
+		log.Printf("verification token for user %v: %v", user.ID, err)
+		return
+	}
+
+	// parsed already to issue the token
+	lifespan, _ := utils.VerifyTokenLifespan()
+
+	lang := utils.Language(c)
+	link := strings.TrimSuffix(base, "/") + "/auth/verify?token=" + url.QueryEscape(token)
+
+	sendMail(c, mailer.Message{
+		To:      user.Email,
+		Subject: utils.NewMessage("mail.verify_subject").Translate(lang),
+		Body:    utils.NewMessage("mail.verify_body", "name", user.Name, "link", link, "hours", fmt.Sprint(lifespan.Hours())).Translate(lang),
+	})
+}
+
+// sendPasswordResetEmail mails user a reset token. The link points to the
+// password reset form at PASSWORD_RESET_URL, without it the email holds the
+// bare token.
+func sendPasswordResetEmail(c *gin.Context, user *models.User) {
+	token, err := user.PasswordResetToken()
+	if err != nil {
+		log.Printf("password reset token for user %v: %v", user.ID, err)
+		return
+	}
+
+	// parsed already to issue the token
+	lifespan, _ := utils.ResetTokenLifespan()
+
+	lang := utils.Language(c)
+	link := token
+	if resetURL := utils.GetEnv("PASSWORD_RESET_URL", ""); resetURL != "" {
+		link = resetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	sendMail(c, mailer.Message{
+		To:      user.Email,
+		Subject: utils.NewMessage("mail.reset_subject").Translate(lang),
+		Body:    utils.NewMessage("mail.reset_body", "name", user.Name, "link", link, "minutes", fmt.Sprint(lifespan.Minutes())).Translate(lang),
+	})
+}
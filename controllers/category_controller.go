@@ -11,13 +11,26 @@ import (
 )
 
 type CategoryInput struct {
-	Name string `json:"name"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+var categoryListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "name", "created_at"},
+	DefaultSort: "name",
+	Filters: []utils.Filter{
+		{Param: "name", Column: "name", Like: true},
+	},
 }
 
 // Get All Categories godoc
 // @Summary     Get all categories.
 // @Tags        Category
 // @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param name query string false "filter by name"
 // @Success     200 {object} []models.Category
 // @Router      /categories [get]
 func GetCategories(c *gin.Context) {
@@ -25,12 +38,21 @@ func GetCategories(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Find(&categories).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	query, err := utils.ParseListQuery(c, categoryListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db, &categories)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, categories)
+	utils.CreatePaginatedResponse(c, http.StatusOK, categories, meta)
 }
 
 // Get Category By ID godoc
@@ -46,7 +68,7 @@ func GetCategory(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	if err := db.Where("id=?", c.Param("id")).First(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
@@ -64,8 +86,8 @@ func GetCategory(c *gin.Context) {
 func CreateCategory(c *gin.Context) {
 	var input CategoryInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -77,13 +99,13 @@ func CreateCategory(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := category.Validate(); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+	if err := category.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Create(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -104,29 +126,30 @@ func UpdateCategory(c *gin.Context) {
 	var category models.Category
 
 	if err := db.Where("id", c.Param("id")).First(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	var input CategoryInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	updated := models.Category{
+		ID:        category.ID,
 		Name:      input.Name,
 		UpdatedAt: time.Now(),
 	}
 
-	if err := updated.Validate(); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+	if err := updated.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Model(&category).Updates(updated).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -146,12 +169,12 @@ func DeleteCategory(c *gin.Context) {
 	var category models.Category
 
 	if err := db.Where("id=?", c.Param("id")).First(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	if err := db.Delete(&category).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"final-project/apperrors"
+	"final-project/mailer"
+	"final-project/models"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errNotFound         = apperrors.NotFound("NOT_FOUND")
+	errArticleOwnerOnly = apperrors.Forbidden("ARTICLE_AUTHOR_ONLY")
+	errPublishDenied    = apperrors.Forbidden("PERMISSION_DENIED", "permission", string(models.PermArticlePublish))
+)
+
+// currentUser returns the user authenticated by the auth middlewares, or nil
+// for anonymous requests.
+func currentUser(c *gin.Context) *models.User {
+	if user, ok := c.Get("user"); ok {
+		u := user.(models.User)
+		return &u
+	}
+
+	return nil
+}
+
+// findEditableArticle loads the article of the :id parameter into article
+// and checks the current user may edit it. Drafts the user can't read are
+// reported as not found.
+func findEditableArticle(c *gin.Context, db *gorm.DB, article *models.Article) error {
+	user := currentUser(c)
+
+	if err := db.Where("id=?", c.Param("id")).First(article).Error; err != nil || !article.IsVisibleTo(user) {
+		return errNotFound
+	}
+
+	if !article.IsEditableBy(user) {
+		return errArticleOwnerOnly
+	}
+
+	return nil
+}
+
+// recordRoleChange audits the role change of user, made by the current user.
+func recordRoleChange(c *gin.Context, db *gorm.DB, user *models.User, old models.UserRole) error {
+	return models.RecordRoleChange(db, &currentUser(c).ID, c.ClientIP(), user, old)
+}
+
+// sendMail sends msg in the background, a slow mail server must not hold up
+// the request. Failures are only logged.
+func sendMail(c *gin.Context, msg mailer.Message) {
+	m := c.MustGet("mailer").(mailer.Mailer)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := m.Send(ctx, msg); err != nil {
+			log.Printf("mail to %v: %v", msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"final-project/apperrors"
+	"final-project/models"
+	"final-project/oidc"
+	"final-project/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcStateCookie keeps the pending authorization request between the
+// redirect to the provider and the callback. It is encrypted, the PKCE code
+// verifier must stay secret.
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateMaxAge = 10 * time.Minute
+)
+
+var (
+	errOIDCStateInvalid = apperrors.BadRequest("OIDC_STATE_INVALID")
+	errOIDCLoginFailed  = apperrors.Unauthorized("OIDC_LOGIN_FAILED")
+)
+
+type oidcState struct {
+	Provider  string           `json:"provider"`
+	Request   oidc.AuthRequest `json:"request"`
+	ExpiresAt int64            `json:"expires_at"`
+}
+
+// OIDC Login godoc
+// @Summary     Login with an identity provider.
+// @Description Redirect to the sign in page of an OpenID Connect provider, which sends the user back to the callback.
+// @Tags        Auth
+// @Param provider path string true "provider name, see OIDC_PROVIDERS"
+// @Success     302
+// @Router      /auth/oidc/{provider} [get]
+func OIDCLogin(c *gin.Context) {
+	name := c.Param("provider")
+
+	provider, ok := oidcProvider(c, name)
+	if !ok {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	req, err := oidc.NewAuthRequest(utils.PublicURL(c, "/auth/oidc/"+name+"/callback"))
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), req)
+	if err != nil {
+		log.Printf("oidc %v: %v", name, err)
+		utils.CreateErrorResponse(c, errOIDCLoginFailed.Wrap(err))
+		return
+	}
+
+	state, err := json.Marshal(oidcState{Provider: name, Request: req, ExpiresAt: time.Now().Add(oidcStateMaxAge).Unix()})
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	encrypted, err := utils.EncryptSecret(string(state))
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	// Lax, the callback is a top level navigation from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, encrypted, int(oidcStateMaxAge.Seconds()), "/auth/oidc", "", secureCookie(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDC Callback godoc
+// @Summary     Identity provider callback.
+// @Description Finish a login started at /auth/oidc/{provider}. Unknown identities are linked to the user with the same email when both the provider and the user verified it, unless that user may publish articles or manage users, else a reader is created. Users with two factor authentication can't login this way.
+// @Tags        Auth
+// @Produce     json
+// @Param provider path string true "provider name"
+// @Param code query string true "authorization code"
+// @Param state query string true "state of the authorization request"
+// @Success     200 {object} models.AuthToken
+// @Router      /auth/oidc/{provider}/callback [get]
+func OIDCCallback(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	name := c.Param("provider")
+
+	provider, ok := oidcProvider(c, name)
+	if !ok {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	state, ok := readOIDCState(c)
+	// the cookie is single use
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", secureCookie(c), true)
+
+	if !ok || state.Provider != name || subtle.ConstantTimeCompare([]byte(state.Request.State), []byte(c.Query("state"))) != 1 {
+		utils.CreateErrorResponse(c, errOIDCStateInvalid)
+		return
+	}
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		utils.CreateErrorResponse(c, errOIDCLoginFailed)
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), state.Request, c.Query("code"))
+	if err != nil {
+		log.Printf("oidc %v: %v", name, err)
+		utils.CreateErrorResponse(c, errOIDCLoginFailed.Wrap(err))
+		return
+	}
+
+	var token *models.AuthToken
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err := models.SignInWithIdentity(tx, name, identity)
+		if err != nil {
+			return err
+		}
+
+		session := models.Session{UserID: user.ID}
+		token, err = session.Issue(tx)
+		return err
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, token)
+}
+
+func oidcProvider(c *gin.Context, name string) (oidc.Provider, bool) {
+	providers := c.MustGet("oidc").(map[string]oidc.Provider)
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+func readOIDCState(c *gin.Context) (*oidcState, bool) {
+	encrypted, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		return nil, false
+	}
+
+	decrypted, err := utils.DecryptSecret(encrypted)
+	if err != nil {
+		return nil, false
+	}
+
+	state := oidcState{}
+	if err := json.Unmarshal([]byte(decrypted), &state); err != nil || state.ExpiresAt < time.Now().Unix() {
+		return nil, false
+	}
+
+	return &state, true
+}
+
+func secureCookie(c *gin.Context) bool {
+	return strings.HasPrefix(utils.PublicURL(c, "/"), "https://")
+}
@@ -11,13 +11,26 @@ import (
 )
 
 type TagInput struct {
-	Name string `json:"name"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+var tagListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "name", "created_at"},
+	DefaultSort: "name",
+	Filters: []utils.Filter{
+		{Param: "name", Column: "name", Like: true},
+	},
 }
 
 // Get All Tags godoc
 // @Summary     Get all tags.
 // @Tags        Tag
 // @Produce     json
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param name query string false "filter by name"
 // @Success     200 {object} []models.Tag
 // @Router      /tags [get]
 func GetTags(c *gin.Context) {
@@ -25,12 +38,21 @@ func GetTags(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Find(&tags).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	query, err := utils.ParseListQuery(c, tagListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db, &tags)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, tags)
+	utils.CreatePaginatedResponse(c, http.StatusOK, tags, meta)
 }
 
 // Get Tag By ID godoc
@@ -46,7 +68,7 @@ func GetTag(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	if err := db.Where("id=?", c.Param("id")).First(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
@@ -64,8 +86,8 @@ func GetTag(c *gin.Context) {
 func CreateTag(c *gin.Context) {
 	var input TagInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -77,13 +99,13 @@ func CreateTag(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := tag.Validate(); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+	if err := tag.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Create(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -104,29 +126,30 @@ func UpdateTag(c *gin.Context) {
 	var tag models.Tag
 
 	if err := db.Where("id", c.Param("id")).First(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	var input TagInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	updated := models.Tag{
+		ID:        tag.ID,
 		Name:      input.Name,
 		UpdatedAt: time.Now(),
 	}
 
-	if err := updated.Validate(); len(err) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err)
+	if err := updated.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Model(&tag).Updates(updated).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -146,12 +169,12 @@ func DeleteTag(c *gin.Context) {
 	var tag models.Tag
 
 	if err := db.Where("id=?", c.Param("id")).First(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	if err := db.Delete(&tag).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
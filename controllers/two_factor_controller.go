@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"final-project/models"
+	"final-project/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ConfirmTOTPInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPInput struct {
+	Password string `json:"password" binding:"required"`
+	// OTP is a TOTP or recovery code
+	OTP string `json:"otp" binding:"required"`
+}
+
+type RegenerateRecoveryCodesInput struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// Enroll TOTP godoc
+// @Summary     Enroll two factor authentication.
+// @Description Create a TOTP secret for the current user, with its otpauth:// URI to show as a QR code. Two factor authentication is enabled once a code is confirmed. Enrolling again replaces the unconfirmed secret.
+// @Tags        Auth
+// @Produce     json
+// @Success     200 {object} models.TOTPEnrollment
+// @Router      /auth/2fa/enroll [post]
+// @Security ApiKeyAuth
+func EnrollTOTP(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := currentUser(c)
+
+	enrollment, err := user.EnrollTOTP(db)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, enrollment)
+}
+
+// Confirm TOTP godoc
+// @Summary     Confirm two factor authentication.
+// @Description Enable two factor authentication with a code of the enrolled secret. The response holds the recovery codes, they are not shown again.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body ConfirmTOTPInput true "a code of the authenticator app"
+// @Success     200 {object} []string
+// @Router      /auth/2fa/confirm [post]
+// @Security ApiKeyAuth
+func ConfirmTOTP(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := currentUser(c)
+
+	var input ConfirmTOTPInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	var codes []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if codes, err = user.ConfirmTOTP(tx, input.Code, time.Now()); err != nil {
+			return err
+		}
+
+		return models.RecordTOTPChange(tx, &user.ID, c.ClientIP(), models.AuditTOTPEnabled, user)
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, codes)
+}
+
+// Disable TOTP godoc
+// @Summary     Disable two factor authentication.
+// @Description Roles which require two factor authentication can't disable it.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body DisableTOTPInput true "the password and a TOTP or recovery code"
+// @Success     200 {object} string
+// @Router      /auth/2fa/disable [post]
+// @Security ApiKeyAuth
+func DisableTOTP(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := currentUser(c)
+
+	var input DisableTOTPInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if !user.TOTPEnabled() {
+		utils.CreateErrorResponse(c, models.ErrTOTPNotEnrolled)
+		return
+	}
+
+	if user.Role.RequiresTOTP() {
+		utils.CreateErrorResponse(c, models.ErrTOTPMandatory)
+		return
+	}
+
+	if err := models.VerifyPassword(user.Password, input.Password); err != nil {
+		utils.CreateErrorResponse(c, models.ErrInvalidCredentials)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := user.VerifySecondFactor(tx, input.OTP, time.Now()); err != nil {
+			return err
+		}
+
+		if err := user.DisableTOTP(tx); err != nil {
+			return err
+		}
+
+		return models.RecordTOTPChange(tx, &user.ID, c.ClientIP(), models.AuditTOTPDisabled, user)
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("totp_disabled"))
+}
+
+// Regenerate Recovery Codes godoc
+// @Summary     Regenerate recovery codes.
+// @Description Replace the recovery codes of the current user, the old ones stop working.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body RegenerateRecoveryCodesInput true "a TOTP or recovery code"
+// @Success     200 {object} []string
+// @Router      /auth/2fa/recovery-codes [post]
+// @Security ApiKeyAuth
+func RegenerateRecoveryCodes(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := currentUser(c)
+
+	var input RegenerateRecoveryCodesInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if !user.TOTPEnabled() {
+		utils.CreateErrorResponse(c, models.ErrTOTPNotEnrolled)
+		return
+	}
+
+	var codes []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := user.VerifySecondFactor(tx, input.OTP, time.Now()); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = user.RegenerateRecoveryCodes(tx)
+		return err
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, codes)
+}
+
+// Reset User TOTP godoc
+// @Summary     Reset two factor authentication of a user.
+// @Description Disable two factor authentication of a user who lost their authenticator and recovery codes. Roles which require it have to enroll again before using their permissions.
+// @Tags        User
+// @Produce     json
+// @Param id path string true "user id"
+// @Success     200 {object} bool
+// @Router      /users/{id}/2fa [delete]
+// @Security ApiKeyAuth
+func ResetUserTOTP(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var user models.User
+
+	if err := db.Where("id=?", c.Param("id")).First(&user).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := user.DisableTOTP(tx); err != nil {
+			return err
+		}
+
+		return models.RecordTOTPChange(tx, &currentUser(c).ID, c.ClientIP(), models.AuditTOTPDisabled, &user)
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, true)
+}
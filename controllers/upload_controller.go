@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"final-project/apperrors"
+	"final-project/models"
+	"final-project/storage"
+	"final-project/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Upload Image godoc
+// @Summary     Upload image.
+// @Description Requires the article:write permission. Upload a JPEG, PNG, GIF or WebP image, at most UPLOAD_MAX_SIZE_MB. Metadata is stripped and thumbnail (300x300), card (800x450) and og (1200x630) variants are generated as JPEG, or PNG for transparent images, and as WebP when smaller. The returned url can be used as an article image_url. With a private bucket the urls are signed for S3_URL_EXPIRY_MINUTES each time they are sent, the signature isn't stored.
+// @Tags        Upload
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param file formData file true "image file"
+// @Success     201 {object} models.Upload
+// @Router      /uploads [post]
+// @Security ApiKeyAuth
+func UploadImage(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	store := c.MustGet("storage").(storage.Storage)
+
+	image, err := utils.UploadImage(c, store, "images", "file")
+
+	if errors.Is(err, http.ErrMissingFile) {
+		err = apperrors.BadRequest("FILE_REQUIRED")
+	}
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	upload := models.Upload{
+		UserID:      currentUser(c).ID,
+		Key:         image.Key,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+		Width:       image.Width,
+		Height:      image.Height,
+		Variants:    models.ImageVariants{},
+	}
+
+	if upload.URL, err = fileURL(c, store, image.Key); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	for name, variant := range image.Variants {
+		url, err := fileURL(c, store, variant.Key)
+		if err != nil {
+			utils.CreateErrorResponse(c, err)
+			return
+		}
+
+		webp := ""
+		if variant.WebPKey != "" {
+			if webp, err = fileURL(c, store, variant.WebPKey); err != nil {
+				utils.CreateErrorResponse(c, err)
+				return
+			}
+		}
+
+		upload.Variants[name] = models.ImageVariant{URL: url, WebP: webp, Width: variant.Width, Height: variant.Height}
+	}
+
+	if err := db.Create(&upload).Error; err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusCreated, upload)
+}
+
+// fileURL returns the absolute URL of the stored file key.
+func fileURL(c *gin.Context, store storage.Storage, key string) (string, error) {
+	url, err := store.URL(key)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.PublicURL(c, url), nil
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"final-project/apperrors"
 	"final-project/models"
-	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"final-project/utils"
@@ -13,27 +15,71 @@ import (
 )
 
 type UserInput struct {
-	Name     string          `json:"name"`
-	Email    string          `json:"email"`
-	Password string          `json:"password"`
-	Role     models.UserRole `json:"role"`
+	Name     string          `json:"name" binding:"required,max=255"`
+	Email    string          `json:"email" binding:"required,email,max=100"`
+	Password string          `json:"password" binding:"required,min=8,max=72"`
+	Role     models.UserRole `json:"role" binding:"required,oneof=admin editor author moderator reader"`
+}
+
+// RegisterInput is UserInput without the role, registered users are
+// readers.
+type RegisterInput struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type UpdateUserInput struct {
+	Name  string          `json:"name" binding:"max=255"`
+	Email string          `json:"email" binding:"omitempty,email,max=100"`
+	Role  models.UserRole `json:"role" binding:"omitempty,oneof=admin editor author moderator reader"`
+}
+
+var userListQuery = utils.QueryOptions{
+	Sorts:       []string{"id", "name", "email", "created_at"},
+	DefaultSort: "id",
+	Filters: []utils.Filter{
+		{Param: "name", Column: "name", Like: true},
+		{Param: "email", Column: "email", Like: true},
+		{Param: "role", Column: "role"},
+	},
 }
 
 type LoginInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// OTP is a TOTP or recovery code, for users with two factor
+	// authentication
+	OTP string `json:"otp"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type UpdateProfileInput struct {
+	Name      string `json:"name" binding:"required,max=255"`
+	AvatarUrl string `json:"avatar_url" binding:"omitempty,httpurl,max=2048"`
+	Bio       string `json:"bio" binding:"max=500"`
 }
 
 type ChangePasswordInput struct {
-	OldPassword string `json:"old_password"`
-	NewPassword string `json:"new_password"`
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
 }
 
 // Get All Users godoc
 // @Summary     Get all users.
 // @Tags        User
 // @Produce     json
-// @Success     200 {object} []models.User
+// @Param page query int false "page number"
+// @Param per_page query int false "items per page (max 100)"
+// @Param cursor query string false "cursor from meta.next_cursor, send empty to start cursor pagination"
+// @Param sort query string false "comma separated sort fields, prefix with '-' for descending"
+// @Param name query string false "filter by name"
+// @Param email query string false "filter by email"
+// @Param role query string false "filter by role"
+// @Success     200 {object} []models.UserProfile
 // @Router      /users [get]
 // @Security ApiKeyAuth
 func GetUsers(c *gin.Context) {
@@ -41,12 +87,21 @@ func GetUsers(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	if err := db.Find(&users).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	query, err := utils.ParseListQuery(c, userListQuery)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	meta, err := query.Find(db, &users)
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, users)
+	utils.CreatePaginatedResponse(c, http.StatusOK, models.UserProfiles(users), meta)
 }
 
 // Get User By ID godoc
@@ -54,7 +109,7 @@ func GetUsers(c *gin.Context) {
 // @Tags        User
 // @Produce     json
 // @Param id path string true "user id"
-// @Success     200 {object} models.User
+// @Success     200 {object} models.UserProfile
 // @Router      /users/{id} [get]
 // @Security ApiKeyAuth
 func GetUser(c *gin.Context) {
@@ -62,11 +117,11 @@ func GetUser(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 	if err := db.Where("id=?", c.Param("id")).First(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, user)
+	utils.CreateResponse(c, http.StatusOK, user.Profile())
 }
 
 // Create User godoc
@@ -74,14 +129,14 @@ func GetUser(c *gin.Context) {
 // @Tags        User
 // @Produce     json
 // @Param Body body UserInput true "body for create user"
-// @Success     200 {object} models.User
+// @Success     200 {object} models.UserProfile
 // @Router      /users [post]
 // @Security ApiKeyAuth
 func CreateUser(c *gin.Context) {
 	var input UserInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -97,21 +152,31 @@ func CreateUser(c *gin.Context) {
 	validate := user.Validate(db)
 
 	if len(validate) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, validate)
+		utils.CreateErrorResponse(c, validate)
 		return
 	}
 
 	if err := user.BeforeSave(db, input.Password); err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	if err := db.Create(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return recordRoleChange(c, tx, &user, "")
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusCreated, &user)
+	sendVerificationEmail(c, &user)
+
+	utils.CreateResponse(c, http.StatusCreated, user.Profile())
 }
 
 // Update User godoc
@@ -119,8 +184,8 @@ func CreateUser(c *gin.Context) {
 // @Tags        User
 // @Produce     json
 // @Param id path string true "user id"
-// @Param Body body UserInput true "body for update user"
-// @Success     200 {object} models.User
+// @Param Body body UpdateUserInput true "body for update user"
+// @Success     200 {object} models.UserProfile
 // @Router      /users/{id} [put]
 // @Security ApiKeyAuth
 func UpdateUser(c *gin.Context) {
@@ -128,37 +193,62 @@ func UpdateUser(c *gin.Context) {
 	var user models.User
 
 	if err := db.Where("id=?", c.Param("id")).First(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
-		return
-	}
-
-	validate := user.Validate(db)
-
-	if len(validate) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, validate)
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	var input UserInput
+	var input UpdateUserInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	var updated models.User
 
+	updated.ID = user.ID
 	updated.Name = input.Name
 	updated.Email = input.Email
 	updated.Role = input.Role
 	updated.UpdatedAt = time.Now()
 
-	if err := db.Model(&user).Updates(updated).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	if err := updated.Validate(db); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, &user)
+	oldRole := user.Role
+	emailChanged := updated.Email != "" && updated.Email != user.Email
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updated).Error; err != nil {
+			return err
+		}
+
+		// the new email is not verified yet
+		if emailChanged {
+			if err := tx.Model(&user).UpdateColumn("email_verified_at", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		if updated.Role == "" || updated.Role == oldRole {
+			return nil
+		}
+
+		return recordRoleChange(c, tx, &updated, oldRole)
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	if emailChanged {
+		sendVerificationEmail(c, &user)
+	}
+
+	utils.CreateResponse(c, http.StatusOK, user.Profile())
 }
 
 // Delete User godoc
@@ -174,12 +264,54 @@ func DeleteUser(c *gin.Context) {
 	var user models.User
 
 	if err := db.Where("id=?", c.Param("id")).First(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
-	if err := db.Delete(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.RevokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, true)
+}
+
+// Unlock User godoc
+// @Summary     Unlock user.
+// @Description Lift the lockout of a user after too many failed logins.
+// @Tags        User
+// @Produce     json
+// @Param id path string true "user id"
+// @Success     200 {object} bool
+// @Router      /users/{id}/unlock [post]
+// @Security ApiKeyAuth
+func UnlockUser(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var user models.User
+
+	if err := db.Where("id=?", c.Param("id")).First(&user).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := user.Unlock(tx); err != nil {
+			return err
+		}
+
+		return models.RecordUnlock(tx, &currentUser(c).ID, c.ClientIP(), &user)
+	})
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -188,17 +320,18 @@ func DeleteUser(c *gin.Context) {
 
 // Login User godoc
 // @Summary     Login user.
-// @Description Login User.
+// @Description Login User. Users with two factor authentication also send a TOTP or recovery code as otp, without it the login fails with TOTP_REQUIRED. Too many failed logins for an email or from an IP lock them out for a while, with 429 and a Retry-After header.
 // @Tags        Auth
 // @Param Body body LoginInput true "the body to login"
 // @Produce     json
+// @Success     200 {object} models.AuthToken
 // @Router      /login [post]
 func LoginUser(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var input LoginInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusBadRequest, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -207,28 +340,88 @@ func LoginUser(c *gin.Context) {
 	u.Email = input.Email
 	u.Password = input.Password
 
-	token, err := u.LoginCheck(db)
+	token, err := u.LoginCheck(db, c.ClientIP(), input.OTP)
+
+	if err != nil {
+		var locked *models.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(locked.Seconds()))
+		}
+
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, token)
+}
+
+// Refresh Token godoc
+// @Summary     Refresh access token.
+// @Description Exchange a refresh token for a new token pair. The refresh token is rotated and can only be used once.
+// @Tags        Auth
+// @Param Body body RefreshTokenInput true "the refresh token"
+// @Produce     json
+// @Success     200 {object} models.AuthToken
+// @Router      /auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var input RefreshTokenInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	session, err := models.FindSessionByRefreshToken(db, input.RefreshToken)
+
+	if err != nil || !session.IsActive() {
+		utils.CreateErrorResponse(c, apperrors.Unauthorized("INVALID_REFRESH_TOKEN"))
+		return
+	}
+
+	token, err := session.Issue(db)
 
 	if err != nil {
-		utils.CreateResponse(c, http.StatusBadRequest, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	utils.CreateResponse(c, http.StatusOK, token)
 }
 
+// Logout User godoc
+// @Summary     Logout user.
+// @Description Revoke the current session, invalidating its access and refresh token.
+// @Tags        Auth
+// @Produce     json
+// @Success     200 {object} bool
+// @Router      /logout [post]
+// @Security ApiKeyAuth
+func LogoutUser(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	session := c.MustGet("session").(models.Session)
+
+	if err := session.Revoke(db); err != nil {
+		utils.CreateErrorResponse(c, err)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, true)
+}
+
 // Register User godoc
 // @Summary     Register user.
+// @Description Registered users are readers, only admins can give them another role.
 // @Tags        Auth
 // @Produce     json
-// @Param Body body UserInput true "body for register user"
-// @Success     200 {object} models.User
+// @Param Body body RegisterInput true "body for register user"
+// @Success     200 {object} models.UserProfile
 // @Router      /register [post]
 func RegisterUser(c *gin.Context) {
-	var input UserInput
+	var input RegisterInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -236,7 +429,7 @@ func RegisterUser(c *gin.Context) {
 		Name:     input.Name,
 		Email:    input.Email,
 		Password: input.Password,
-		Role:     input.Role,
+		Role:     models.READER,
 	}
 
 	db := c.MustGet("db").(*gorm.DB)
@@ -244,24 +437,23 @@ func RegisterUser(c *gin.Context) {
 	validate := user.Validate(db)
 
 	if len(validate) > 0 {
-		utils.CreateResponse(c, http.StatusUnprocessableEntity, validate)
+		utils.CreateErrorResponse(c, validate)
 		return
 	}
 
 	if err := user.BeforeSave(db, input.Password); err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Create(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	fmt.Println("User => ", user)
-	// fmt.Println("Is Match => ", user.Password == )
+	sendVerificationEmail(c, &user)
 
-	utils.CreateResponse(c, http.StatusCreated, &user)
+	utils.CreateResponse(c, http.StatusCreated, user.Profile())
 }
 
 // Change Password User godoc
@@ -269,34 +461,29 @@ func RegisterUser(c *gin.Context) {
 // @Tags        Auth
 // @Produce     json
 // @Param Body body ChangePasswordInput true "body for change user password"
-// @Success     200 {object} models.User
+// @Success     200 {object} string
 // @Router      /change-password [patch]
 // @Security ApiKeyAuth
 func ChangePassword(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	userID, err := utils.ExtractTokenID(c)
-
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
+	userID := currentUser(c).ID
 
 	var input ChangePasswordInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.CreateResponse(c, http.StatusBadRequest, err.Error())
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	u := models.User{}
 
 	if err := db.Model(models.User{}).Where("id=?", userID).Take(&u).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, err.Error())
+		utils.CreateErrorResponse(c, errNotFound)
 		return
 	}
 
 	if err := models.VerifyPassword(u.Password, input.OldPassword); err != nil {
-		utils.CreateResponse(c, http.StatusBadRequest, "Password lama tidak cocok")
+		utils.CreateErrorResponse(c, apperrors.BadRequest("OLD_PASSWORD_MISMATCH"))
 		return
 	}
 
@@ -304,30 +491,55 @@ func ChangePassword(c *gin.Context) {
 	updated.Password = input.NewPassword
 
 	if err := updated.BeforeSave(db, input.NewPassword); err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
 	if err := db.Model(&u).Updates(updated).Error; err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, "Berhasil ganti password")
+	utils.CreateResponse(c, http.StatusOK, utils.NewMessage("password_changed"))
 }
 
 // Get User Profile godoc
 // @Summary     Get user profile.
 // @Tags        Auth
 // @Produce     json
-// @Success     200 {object} models.User
+// @Success     200 {object} models.UserProfile
 // @Router      /my-profile [get]
 // @Security ApiKeyAuth
 func MyProfile(c *gin.Context) {
-	userID, err := utils.ExtractTokenID(c)
+	userID := currentUser(c).ID
 
-	if err != nil {
-		utils.CreateResponse(c, http.StatusInternalServerError, err.Error())
+	var user models.User
+
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Where("id=?", userID).First(&user).Error; err != nil {
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	utils.CreateResponse(c, http.StatusOK, user.Profile())
+}
+
+// Update User Profile godoc
+// @Summary     Update user profile.
+// @Description Update the name, avatar and bio shown next to the articles and comments of the current user.
+// @Tags        Auth
+// @Produce     json
+// @Param Body body UpdateProfileInput true "body for update user profile"
+// @Success     200 {object} models.UserProfile
+// @Router      /my-profile [put]
+// @Security ApiKeyAuth
+func UpdateMyProfile(c *gin.Context) {
+	userID := currentUser(c).ID
+
+	var input UpdateProfileInput
+
+	if err := utils.BindJSON(c, &input); len(err) > 0 {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
@@ -335,9 +547,28 @@ func MyProfile(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 	if err := db.Where("id=?", userID).First(&user).Error; err != nil {
-		utils.CreateResponse(c, http.StatusNotFound, "data not found")
+		utils.CreateErrorResponse(c, errNotFound)
+		return
+	}
+
+	user.Name = input.Name
+	user.AvatarUrl = models.StoredFileURL(input.AvatarUrl)
+	user.Bio = input.Bio
+	user.UpdatedAt = time.Now()
+
+	// the signature of an uploaded avatar isn't stored, see StoredFileURL
+	if len(user.AvatarUrl) > 255 {
+		utils.CreateErrorResponse(c, utils.FieldErrors{utils.NewFieldError("avatar_url", "max", "255")})
+		return
+	}
+
+	// Select, so the avatar and the bio can be cleared
+	err := db.Model(&user).Select("name", "avatar_url", "bio", "updated_at").Updates(&user).Error
+
+	if err != nil {
+		utils.CreateErrorResponse(c, err)
 		return
 	}
 
-	utils.CreateResponse(c, http.StatusOK, user)
+	utils.CreateResponse(c, http.StatusOK, user.Profile())
 }
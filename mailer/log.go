@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Log writes emails to the log, or as files under Dir when it is set, for
+// local development and testing.
+type Log struct {
+	Dir string
+}
+
+func (l *Log) Send(_ context.Context, msg Message) error {
+	content := fmt.Sprintf("To: %v\nSubject: %v\n\n%v\n", msg.To, msg.Subject, msg.Body)
+
+	if l.Dir == "" {
+		log.Printf("mail\n%v", content)
+		return nil
+	}
+
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%v-%v.eml", time.Now().Format("20060102-150405.000000000"), strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To))
+
+	return os.WriteFile(filepath.Join(l.Dir, name), []byte(content), 0644)
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"final-project/config"
 	"final-project/docs"
+	"final-project/models"
 	"final-project/routes"
 	"final-project/utils"
+	"final-project/workers"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	_ "final-project/docs"
 
@@ -51,10 +60,57 @@ func main() {
 	docs.SwaggerInfo.Host = utils.GetEnv("SWAGGER_HOST", "localhost:8080")
 	docs.SwaggerInfo.Schemes = swaggerSchemes
 
+	config.LoadSigningKeys()
+
 	db := config.ConnectDB()
 	sqlDB, _ := db.DB()
 	defer sqlDB.Close()
 
-	r := routes.SetupRouter(db)
-	r.Run()
+	config.BootstrapAdmin(db)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	publishInterval, err := strconv.Atoi(utils.GetEnv("PUBLISHER_INTERVAL_SECONDS", "60"))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	var wg sync.WaitGroup
+	publisher := workers.NewPublisher(db, time.Duration(publishInterval)*time.Second)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		publisher.Run(ctx)
+	}()
+
+	store := config.ConnectStorage()
+	models.SetFileStorage(store)
+	mail := config.ConnectMailer()
+	providers := config.ConnectOIDC()
+
+	r := routes.SetupRouter(db, store, mail, providers)
+	srv := &http.Server{
+		Addr:    ":" + utils.GetEnv("PORT", "8080"),
+		Handler: r,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println(err.Error())
+	}
+
+	wg.Wait()
 }
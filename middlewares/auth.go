@@ -1,20 +1,99 @@
 package middlewares
 
 import (
+	"final-project/apperrors"
+	"final-project/models"
 	"final-project/utils"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+var (
+	errRevokedToken     = apperrors.Unauthorized("TOKEN_REVOKED")
+	errAPIKeyNotAllowed = apperrors.Forbidden("API_KEY_NOT_ALLOWED")
+)
+
+// apiKeyHeader carries the API keys of machine clients, in place of a token.
+const apiKeyHeader = "X-API-Key"
+
 func JwtAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := utils.TokenValid(c)
-		if err != nil {
-			utils.CreateResponse(c, http.StatusUnauthorized, err.Error())
+		if err := authenticate(c); err != nil {
+			utils.CreateErrorResponse(c, err)
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
+
+// OptionalJwtAuth authenticates the request when it carries a token and
+// lets anonymous requests through, for public routes whose response depends
+// on who is asking.
+func OptionalJwtAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if utils.ExtractToken(c) == "" && c.GetHeader(apiKeyHeader) == "" {
+			c.Next()
+			return
+		}
+
+		if err := authenticate(c); err != nil {
+			utils.CreateErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession refuses requests authenticated by an API key, for actions
+// only the user itself may take, such as changing its password or creating
+// API keys. It relies on JwtAuth.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("session"); !ok {
+			utils.CreateErrorResponse(c, errAPIKeyNotAllowed)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate checks the token and its session, then stores the session
+// and its user in the context as "session" and "user". Requests with an API
+// key get "api_key" instead of "session", and their user is restricted to
+// the scopes of the key.
+func authenticate(c *gin.Context) error {
+	db := c.MustGet("db").(*gorm.DB)
+
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		apiKey, err := models.FindAPIKey(db, key)
+		if err != nil {
+			return err
+		}
+
+		user := apiKey.User
+		user.RestrictTo(apiKey.Scopes)
+
+		c.Set("api_key", *apiKey)
+		c.Set("user", user)
+		return nil
+	}
+
+	sessionID, err := utils.ExtractTokenSessionID(c)
+	if err != nil {
+		return err
+	}
+
+	session := models.Session{}
+
+	if err := db.Joins("User").First(&session, sessionID).Error; err != nil || !session.IsActive() {
+		return errRevokedToken
+	}
+
+	c.Set("session", session)
+	c.Set("user", session.User)
+	return nil
+}
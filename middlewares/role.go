@@ -1,34 +1,33 @@
 package middlewares
 
 import (
+	"final-project/apperrors"
 	"final-project/models"
 	"final-project/utils"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func AdminOnly() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		db := c.MustGet("db").(*gorm.DB)
-		userID, err := utils.ExtractTokenID(c)
-		user := models.User{}
+var errTOTPEnrollmentRequired = apperrors.Forbidden("TOTP_ENROLLMENT_REQUIRED")
 
-		if err != nil {
-			utils.CreateResponse(c, http.StatusUnauthorized, err.Error())
-			c.Abort()
-			return
-		}
+// RequirePermission lets through the users whose role has every permission
+// in perms. It relies on JwtAuth for the user.
+func RequirePermission(perms ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(models.User)
 
-		if err := db.Where("id=?", userID).First(&user).Error; err != nil {
-			utils.CreateResponse(c, http.StatusNotFound, "user : "+err.Error())
-			c.Abort()
-			return
-		}
+		for _, p := range perms {
+			if user.Can(p) {
+				continue
+			}
 
-		if user.Role != "admin" {
-			utils.CreateResponse(c, http.StatusBadRequest, "hanya admin yang dapat melakukan aksi ini")
+			// the role has p, but only once two factor authentication is on,
+			// else p is missing from the role or the scopes of the API key
+			if user.Role.Can(p) && user.Role.RequiresTOTP() && !user.TOTPEnabled() {
+				utils.CreateErrorResponse(c, errTOTPEnrollmentRequired)
+			} else {
+				utils.CreateErrorResponse(c, apperrors.Forbidden("PERMISSION_DENIED", "permission", string(p)))
+			}
 			c.Abort()
 			return
 		}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"final-project/apperrors"
+	"final-project/utils"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to spot.
+const apiKeyPrefix = "bk_"
+
+// apiKeyUsageInterval is how often, at most, the last use of a key is
+// written, not to write on every request.
+const apiKeyUsageInterval = time.Minute
+
+var ErrInvalidAPIKey = apperrors.Unauthorized("INVALID_API_KEY")
+
+// APIKeyScopes are the permissions an API key may use, out of those of its
+// user. It is stored as a jsonb column.
+type APIKeyScopes []Permission
+
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	var b []byte
+
+	switch value := value.(type) {
+	case nil:
+		*s = APIKeyScopes{}
+		return nil
+	case []byte:
+		b = value
+	case string:
+		b = []byte(value)
+	default:
+		return errors.New("scopes: unsupported value type")
+	}
+
+	return json.Unmarshal(b, s)
+}
+
+// APIKey authenticates a machine client as its user, through the X-API-Key
+// header. Only the hash of the key is stored, Prefix tells keys apart.
+type APIKey struct {
+	ID         uint         `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     uint         `gorm:"not null;index" json:"user_id"`
+	Name       string       `gorm:"size:100;not null" json:"name"`
+	Prefix     string       `gorm:"size:16;not null" json:"prefix"`
+	KeyHash    string       `gorm:"size:64;not null;unique" json:"-"`
+	Scopes     APIKeyScopes `gorm:"type:jsonb;not null;default:'[]'" json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedAt  time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	User       User         `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
+
+// Validate checks the scopes are permissions. Whether the user has them is
+// up to the caller.
+func (k *APIKey) Validate() utils.FieldErrors {
+	errs := utils.FieldErrors{}
+
+	for _, scope := range k.Scopes {
+		if !slices.Contains(AllPermissions, scope) {
+			names := make([]string, len(AllPermissions))
+			for i, p := range AllPermissions {
+				names[i] = string(p)
+			}
+
+			errs = append(errs, utils.NewFieldError("scopes", "oneof", strings.Join(names, " ")))
+			break
+		}
+	}
+
+	return errs
+}
+
+// Generate creates the secret of k, returned only here, and sets its hash
+// and prefix.
+func (k *APIKey) Generate() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	k.Prefix = key[:len(apiKeyPrefix)+8]
+	k.KeyHash = utils.HashToken(key)
+
+	return key, nil
+}
+
+func (k *APIKey) Revoke(db *gorm.DB) error {
+	now := time.Now()
+	k.RevokedAt = &now
+	return db.Model(k).Update("revoked_at", now).Error
+}
+
+// FindAPIKey returns the active API key key, with its user, and records its
+// use.
+func FindAPIKey(db *gorm.DB, key string) (*APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey := APIKey{}
+
+	if err := db.Joins("User").Where("key_hash = ?", utils.HashToken(key)).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if !apiKey.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	err := db.Model(&APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-apiKeyUsageInterval)).
+		UpdateColumn("last_used_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
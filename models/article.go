@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"final-project/utils"
 	"fmt"
 	"strings"
 	"time"
@@ -13,38 +15,91 @@ type Article struct {
 	ID          uint              `gorm:"primary_key;auto_increment" json:"id"`
 	Title       string            `gorm:"size:100;unique;not null" json:"title"`
 	ImageUrl    string            `gorm:"size:255;not null" json:"image_url"`
+	Images      ImageVariants     `gorm:"type:jsonb;not null;default:'{}'" json:"images"`
 	Slug        string            `gorm:"size:100;unique;not null" json:"slug"`
 	Content     string            `gorm:"not null" json:"content"`
 	Description string            `gorm:"size:255;not null" json:"description"`
 	IsPublished bool              `gorm:"not null" json:"is_published"`
+	PublishAt   *time.Time        `gorm:"index" json:"publish_at"`
 	UserID      uint              `json:"user_id"`
 	CreatedAt   time.Time         `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time         `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	Tags        []ArticleTag      `gorm:"many2many" json:"tags"`
-	Categories  []ArticleCategory `gorm:"many2many" json:"categories"`
-	Comments    []ArticleComment  `gorm:"many2many" json:"-"`
+	Tags        []ArticleTag      `json:"tags"`
+	Categories  []ArticleCategory `json:"categories"`
+	Comments    []ArticleComment  `json:"-"`
 	User        User              `json:"author"`
 }
 
-func (a *Article) Validate(_ *gorm.DB) []string {
-	errs := []string{}
+// MarshalJSON sends the images as clients can download them, see FileURL.
+func (a Article) MarshalJSON() ([]byte, error) {
+	type article Article
 
-	if a.Title == "" {
-		errs = append(errs, "Title artikel harus diisi")
+	readable := article(a)
+	readable.ImageUrl = FileURL(a.ImageUrl)
+	readable.Images = a.Images.readable()
+
+	return json.Marshal(readable)
+}
+
+// Validate checks what the binding tags of the input can't: that the slug
+// is free and that the image URL fits once stored, see StoredFileURL.
+func (a *Article) Validate(db *gorm.DB) utils.FieldErrors {
+	errs := utils.FieldErrors{}
+
+	if len(a.ImageUrl) > 255 {
+		errs = append(errs, utils.NewFieldError("image_url", "max", "255"))
+	}
+
+	if ArticleTitleTaken(db, a.Title, a.ID) {
+		errs = append(errs, utils.NewFieldError("title", "unique", ""))
 	}
 
-	if a.ImageUrl != "" && !strings.Contains(a.ImageUrl, "http://") && !strings.Contains(a.ImageUrl, "https://") {
-		errs = append(errs, "Image url tidak valid")
+	if ArticleSlugTaken(db, a.Slug, a.ID) {
+		errs = append(errs, utils.NewFieldError("slug", "unique", ""))
 	}
 
 	return errs
 }
 
-func (a *Article) GetSlug(_ *gorm.DB) {
-	slugSlice := strings.Split(a.Title, " ")
-	slug := strings.ToLower(strings.Join(slugSlice, "-"))
+// ArticleTitleTaken reports whether title is the title of another article
+// than articleID.
+func ArticleTitleTaken(db *gorm.DB, title string, articleID uint) bool {
+	var count int64
+
+	db.Model(&Article{}).Where("title = ? AND id <> ?", title, articleID).Count(&count)
+	return count > 0
+}
+
+// PublishDueArticles publishes the drafts whose publish_at has passed and
+// returns how many were published.
+func PublishDueArticles(db *gorm.DB, now time.Time) (int64, error) {
+	res := db.Model(&Article{}).
+		Where("is_published = ? AND publish_at <= ?", false, now).
+		UpdateColumns(map[string]interface{}{
+			"is_published": true,
+			"publish_at":   nil,
+			"updated_at":   now,
+		})
+
+	return res.RowsAffected, res.Error
+}
+
+// GetSlug sets the slug to custom, checked by Validate, or when custom is
+// empty to a unique slug made from the title.
+func (a *Article) GetSlug(db *gorm.DB, custom string) {
+	if custom != "" {
+		a.Slug = custom
+		return
+	}
 
-	a.Slug = slug
+	a.Slug = UniqueArticleSlug(db, utils.Slugify(a.Title), a.ID)
+}
+
+// SyncImages stores the variants of the uploaded image at ImageUrl, see
+// FindImageVariants.
+func (a *Article) SyncImages(db *gorm.DB) error {
+	a.Images = FindImageVariants(db, a.ImageUrl)
+	return db.Model(a).UpdateColumn("images", a.Images).Error
 }
 
 func (a *Article) Delete(db *gorm.DB) error {
@@ -64,6 +119,14 @@ func (a *Article) Delete(db *gorm.DB) error {
 		return err
 	}
 
+	if err := db.Where("article_id=?", a.ID).Delete(&ArticleRevision{}).Error; err != nil {
+		return err
+	}
+
+	if err := db.Where("article_id=?", a.ID).Delete(&ArticleSlug{}).Error; err != nil {
+		return err
+	}
+
 	if err := db.Delete(&article).Error; err != nil {
 		return err
 	}
@@ -71,153 +134,164 @@ func (a *Article) Delete(db *gorm.DB) error {
 	return nil
 }
 
-func (a *Article) RestoreUpdate(db *gorm.DB, details *Article) {
-	fmt.Println("details => ", details)
-
-	category_ids := []uint{}
-	for _, c := range details.Categories {
-		category_ids = append(category_ids, c.CategoryID)
-	}
-
-	tag_ids := []uint{}
-	for _, t := range details.Tags {
-		tag_ids = append(tag_ids, t.TagID)
+// ClearRelations removes the article's tags and categories, so they can be
+// inserted again on update.
+func (a *Article) ClearRelations(db *gorm.DB) error {
+	if err := db.Where("article_id=?", a.ID).Delete(&ArticleCategory{}).Error; err != nil {
+		return err
 	}
 
-	db.Model(&a).Updates(details)
-	a.InsertCategories(db, category_ids)
-	a.InsertTags(db, tag_ids, []string{})
+	return db.Where("article_id=?", a.ID).Delete(&ArticleTag{}).Error
 }
 
-func (a *Article) BeforeUpdate(db *gorm.DB) error {
-	var category ArticleCategory
-	var tag ArticleTag
+// InsertCategories links the article to the categories in ids. Unknown ids
+// are reported as utils.FieldErrors.
+func (a *Article) InsertCategories(db *gorm.DB, ids []uint) error {
+	ids = uniqueIDs(ids)
 
-	if err := db.Where("article_id=?", a.ID).Delete(&category).Error; err != nil {
-		return err
+	if len(ids) == 0 {
+		return nil
 	}
 
-	if err := db.Where("article_id=?", a.ID).Delete(&tag).Error; err != nil {
+	var found []uint
+	if err := db.Model(&Category{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (a *Article) InsertCategories(db *gorm.DB, ids []uint) []string {
-	errs := []string{}
+	missing := []string{}
+	rows := []ArticleCategory{}
 
-	if len(ids) > 0 && ids[0] != 0 {
-		for _, id := range ids {
-			ca := Category{}
-
-			if err := db.Where("id=?", id).First(&ca).Error; err != nil {
-				errs = append(errs, err.Error())
-			}
+	for _, id := range ids {
+		if !slices.Contains(found, id) {
+			missing = append(missing, fmt.Sprint(id))
+			continue
+		}
 
-			caInput := ArticleCategory{
-				ArticleID:  a.ID,
-				CategoryID: uint(id),
-				CreatedAt:  time.Now(),
-				UpdatedAt:  time.Now(),
-			}
+		rows = append(rows, ArticleCategory{
+			ArticleID:  a.ID,
+			CategoryID: id,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		})
+	}
 
-			if err := db.Create(&caInput).Error; err != nil {
-				errs = append(errs, err.Error())
-			}
-		}
+	if len(missing) > 0 {
+		return utils.FieldErrors{utils.NewFieldError("category_ids", "exists", strings.Join(missing, ","))}
 	}
 
-	return errs
+	return db.Create(&rows).Error
 }
 
-func (a *Article) InsertTags(db *gorm.DB, ids []uint, tags []string) []string {
-	errs := []string{}
+// InsertTags links the article to the tags in ids and to the tags named in
+// names, creating the ones that don't exist yet. Unknown ids are reported as
+// utils.FieldErrors.
+func (a *Article) InsertTags(db *gorm.DB, ids []uint, names []string) error {
+	ids = uniqueIDs(ids)
 
-	if len(ids) > 0 && ids[0] != 0 {
-		for _, id := range ids {
-			ca := Tag{}
-
-			if err := db.Where("id=?", id).First(&ca).Error; err != nil {
-				errs = append(errs, err.Error())
-			}
+	if len(ids) > 0 {
+		var found []uint
+		if err := db.Model(&Tag{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return err
+		}
 
-			tInput := ArticleTag{
-				ArticleID: a.ID,
-				TagID:     uint(id),
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
+		missing := []string{}
+		for _, id := range ids {
+			if !slices.Contains(found, id) {
+				missing = append(missing, fmt.Sprint(id))
 			}
+		}
 
-			if err := db.Create(&tInput).Error; err != nil {
-				errs = append(errs, err.Error())
-			}
+		if len(missing) > 0 {
+			return utils.FieldErrors{utils.NewFieldError("tag_ids", "exists", strings.Join(missing, ","))}
 		}
 	}
 
-	fmt.Println("t => ", tags)
-
-	if len(tags) > 0 && tags[0] != "" {
-		for _, name := range tags {
-			existTag := Tag{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
 
-			tInput := ArticleTag{
-				ArticleID: a.ID,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
+		tag := Tag{}
+		if err := db.Where(Tag{Name: name}).Attrs(Tag{CreatedAt: time.Now(), UpdatedAt: time.Now()}).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
 
-			if err := db.Where("name=?", name).First(&existTag).Error; err != nil {
-				tag := &Tag{
-					Name:      name,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}
+		if !slices.Contains(ids, tag.ID) {
+			ids = append(ids, tag.ID)
+		}
+	}
 
-				if err := db.Create(&tag).Error; err != nil {
-					fmt.Println(err.Error())
-				}
+	if len(ids) == 0 {
+		return nil
+	}
 
-				tInput.TagID = tag.ID
+	rows := []ArticleTag{}
+	for _, id := range ids {
+		rows = append(rows, ArticleTag{
+			ArticleID: a.ID,
+			TagID:     id,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+	}
 
-				if err := db.Create(&tInput).Error; err != nil {
-					errs = append(errs, err.Error())
-				}
-			}
+	return db.Create(&rows).Error
+}
 
-			if !slices.Contains(ids, existTag.ID) {
-				tInput.TagID = existTag.ID
+// uniqueIDs drops zero ids, which match no row, and duplicates.
+func uniqueIDs(ids []uint) []uint {
+	unique := []uint{}
 
-				if err := db.Create(&tInput).Error; err != nil {
-					errs = append(errs, err.Error())
-				}
-			}
+	for _, id := range ids {
+		if id != 0 && !slices.Contains(unique, id) {
+			unique = append(unique, id)
 		}
 	}
 
-	return errs
+	return unique
 }
 
-func (a *Article) GetDetails(db *gorm.DB) {
-	categories := []ArticleCategory{}
-	tags := []ArticleTag{}
+// ArticleVisibleTo limits the queried articles to the ones user may read:
+// published articles for everyone, drafts only for their author and users
+// allowed to manage articles. user is nil for anonymous requests.
+func ArticleVisibleTo(user *User) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user == nil {
+			return db.Where("articles.is_published = ?", true)
+		}
 
-	db.Where("article_id=?", a.ID).Find(&a.Categories)
-	db.Where("article_id=?", a.ID).Find(&a.Tags)
-	db.Where("id=?", a.UserID).First(&a.User)
+		if user.Can(PermArticleManage) {
+			return db
+		}
 
-	for _, category := range a.Categories {
-		db.Where("id = ?", category.CategoryID).First(&category.Category)
-		categories = append(categories, category)
+		return db.Where("articles.is_published = ? OR articles.user_id = ?", true, user.ID)
 	}
+}
 
-	for _, tag := range a.Tags {
-		db.Where("id = ?", tag.TagID).First(&tag.Tag)
-		tags = append(tags, tag)
+func (a *Article) IsVisibleTo(user *User) bool {
+	if a.IsPublished {
+		return true
 	}
 
-	a.Categories = categories
-	a.Tags = tags
+	return user.Can(PermArticleManage) || (user != nil && user.ID == a.UserID)
+}
+
+// IsEditableBy reports whether user may edit or delete the article: users
+// allowed to manage articles may edit any, writers only their own.
+func (a *Article) IsEditableBy(user *User) bool {
+	return user.Can(PermArticleManage) || (user.Can(PermArticleWrite) && user.ID == a.UserID)
+}
+
+// ArticleDetails preloads the author, categories and tags of the queried
+// articles. Each relation costs one batched query, however many articles
+// are loaded.
+func ArticleDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Categories.Category").Preload("Tags.Tag")
+}
+
+func (a *Article) GetDetails(db *gorm.DB) error {
+	return db.Scopes(ArticleDetails).First(a, a.ID).Error
 }
 
 type ArticleTag struct {
@@ -253,17 +327,6 @@ type ArticleComment struct {
 	// Replies   []ReplyArticleComment `json:"replies"`
 }
 
-func (co *ArticleComment) GetDetails(db *gorm.DB) error {
-	user := User{}
-
-	if err := db.Where("id=?", co.UserID).First(&user).Error; err != nil {
-		return err
-	}
-
-	co.User = user
-	return nil
-}
-
 type ReplyArticleComment struct {
 	ID        uint           `gorm:"primary_key;auto_increment" json:"id"`
 	UserID    uint           `json:"user_id"`
@@ -278,31 +341,8 @@ type ReplyArticleComment struct {
 	User      User           `json:"user"`
 }
 
-func (r *ReplyArticleComment) GetParent(db *gorm.DB) error {
-	var parent ArticleComment
-	var user User
-
-	if err := db.Where("id=?", r.ParentID).First(&parent).Error; err != nil {
-		return err
-	}
-
-	if err := db.Where("id", parent.UserID).First(&user).Error; err != nil {
-		return err
-	}
-
-	r.Parent = parent
-	r.Parent.User = user
-
-	return nil
-}
-
-func (co *ReplyArticleComment) GetDetails(db *gorm.DB) error {
-	user := User{}
-
-	if err := db.Where("id=?", co.UserID).First(&user).Error; err != nil {
-		return err
-	}
-
-	co.User = user
-	return nil
+// ReplyDetails preloads the author of the queried replies and of the
+// comments they reply to.
+func ReplyDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Parent.User")
 }
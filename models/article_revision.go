@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"final-project/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ArticleRevision is an immutable snapshot of an article, saved after every
+// change. Revision numbers start at 1 for each article.
+type ArticleRevision struct {
+	ID          uint      `gorm:"primary_key;auto_increment" json:"id"`
+	ArticleID   uint      `gorm:"not null;uniqueIndex:idx_article_revision" json:"article_id"`
+	Revision    uint      `gorm:"not null;uniqueIndex:idx_article_revision" json:"revision"`
+	UserID      uint      `json:"user_id"`
+	Title       string    `gorm:"size:100;not null" json:"title"`
+	Slug        string    `gorm:"size:100;not null" json:"slug"`
+	ImageUrl    string    `gorm:"size:255;not null" json:"image_url"`
+	Content     string    `gorm:"not null" json:"content"`
+	Description string    `gorm:"size:255;not null" json:"description"`
+	TagIDs      string    `json:"tag_ids"`
+	CategoryIDs string    `json:"category_ids"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	User        User      `json:"editor"`
+}
+
+type RevisionChange struct {
+	Field string           `json:"field"`
+	From  string           `json:"from"`
+	To    string           `json:"to"`
+	Lines []utils.DiffLine `json:"lines,omitempty"`
+}
+
+// MarshalJSON sends the image as clients can download it, see FileURL.
+func (r ArticleRevision) MarshalJSON() ([]byte, error) {
+	type revision ArticleRevision
+
+	readable := revision(r)
+	readable.ImageUrl = FileURL(r.ImageUrl)
+
+	return json.Marshal(readable)
+}
+
+func (r *ArticleRevision) BeforeUpdate(_ *gorm.DB) error {
+	return errors.New("article revisions are immutable")
+}
+
+// SaveRevision snapshots a, which must have its tags and categories loaded,
+// as the next revision edited by editorID.
+func (a *Article) SaveRevision(db *gorm.DB, editorID uint) (*ArticleRevision, error) {
+	var last uint
+
+	if err := db.Model(&ArticleRevision{}).Where("article_id=?", a.ID).Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+		return nil, err
+	}
+
+	tagIDs := []string{}
+	for _, t := range a.Tags {
+		tagIDs = append(tagIDs, strconv.Itoa(int(t.TagID)))
+	}
+
+	categoryIDs := []string{}
+	for _, c := range a.Categories {
+		categoryIDs = append(categoryIDs, strconv.Itoa(int(c.CategoryID)))
+	}
+
+	revision := ArticleRevision{
+		ArticleID:   a.ID,
+		Revision:    last + 1,
+		UserID:      editorID,
+		Title:       a.Title,
+		Slug:        a.Slug,
+		ImageUrl:    a.ImageUrl,
+		Content:     a.Content,
+		Description: a.Description,
+		TagIDs:      strings.Join(tagIDs, ","),
+		CategoryIDs: strings.Join(categoryIDs, ","),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := db.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// Diff lists the fields changed from r to other. Long text fields come with
+// a line diff.
+func (r *ArticleRevision) Diff(other *ArticleRevision) []RevisionChange {
+	changes := []RevisionChange{}
+
+	fields := []struct {
+		name     string
+		from, to string
+		lines    bool
+	}{
+		{"title", r.Title, other.Title, false},
+		{"slug", r.Slug, other.Slug, false},
+		{"image_url", r.ImageUrl, other.ImageUrl, false},
+		{"description", r.Description, other.Description, true},
+		{"content", r.Content, other.Content, true},
+		{"tag_ids", r.TagIDs, other.TagIDs, false},
+		{"category_ids", r.CategoryIDs, other.CategoryIDs, false},
+	}
+
+	for _, f := range fields {
+		if f.from == f.to {
+			continue
+		}
+
+		change := RevisionChange{Field: f.name, From: f.from, To: f.to}
+
+		if f.lines {
+			change.Lines = utils.DiffLines(f.from, f.to)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
@@ -0,0 +1,86 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	searchTitleOptions   = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	searchSnippetOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+type ArticleHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+type ArticleSearchResult struct {
+	Article   Article          `json:"article"`
+	Rank      float64          `json:"rank"`
+	Highlight ArticleHighlight `json:"highlight"`
+}
+
+type articleSearchHit struct {
+	ID          uint
+	Rank        float64
+	Title       string
+	Description string
+	Content     string
+}
+
+// SearchArticles ranks the articles visible to user against q using the
+// search_vector full-text index, see config/migrations.go. q accepts the
+// web search syntax: quoted phrases, "or" and "-" to exclude words.
+func SearchArticles(db *gorm.DB, user *User, q string, limit, offset int) ([]ArticleSearchResult, int64, error) {
+	var total int64
+	var hits []articleSearchHit
+
+	query := db.Table("articles, websearch_to_tsquery('simple', ?) AS query", q).
+		Where("articles.search_vector @@ query").
+		Scopes(ArticleVisibleTo(user))
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Select(
+		"articles.id, ts_rank(articles.search_vector, query) AS rank, "+
+			"ts_headline('simple', articles.title, query, ?) AS title, "+
+			"ts_headline('simple', articles.description, query, ?) AS description, "+
+			"ts_headline('simple', articles.content, query, ?) AS content",
+		searchTitleOptions, searchSnippetOptions, searchSnippetOptions,
+	).Order("rank DESC, articles.id DESC").Limit(limit).Offset(offset).Scan(&hits).Error
+
+	if err != nil || len(hits) == 0 {
+		return []ArticleSearchResult{}, total, err
+	}
+
+	ids := []uint{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	var articles []Article
+	if err := db.Scopes(ArticleDetails).Where("id IN ?", ids).Find(&articles).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byID := map[uint]Article{}
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	results := []ArticleSearchResult{}
+	for _, hit := range hits {
+		results = append(results, ArticleSearchResult{
+			Article: byID[hit.ID],
+			Rank:    hit.Rank,
+			Highlight: ArticleHighlight{
+				Title:       hit.Title,
+				Description: hit.Description,
+				Content:     hit.Content,
+			},
+		})
+	}
+
+	return results, total, nil
+}
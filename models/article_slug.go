@@ -0,0 +1,68 @@
+package models
+
+import (
+	"final-project/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ArticleSlug is a slug an article used before, kept so that old links
+// redirect to the current slug.
+type ArticleSlug struct {
+	ID        uint      `gorm:"primary_key;auto_increment" json:"id"`
+	ArticleID uint      `gorm:"not null;index" json:"article_id"`
+	Slug      string    `gorm:"size:100;not null;unique" json:"slug"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// defaultSlug is used for titles without any letter or digit to keep.
+const defaultSlug = "artikel"
+
+// ArticleSlugTaken reports whether slug is, or was, the slug of another
+// article than articleID.
+func ArticleSlugTaken(db *gorm.DB, slug string, articleID uint) bool {
+	var count int64
+
+	db.Model(&Article{}).Where("slug = ? AND id <> ?", slug, articleID).Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	db.Model(&ArticleSlug{}).Where("slug = ? AND article_id <> ?", slug, articleID).Count(&count)
+	return count > 0
+}
+
+// UniqueArticleSlug returns base, or base followed by the first free
+// numeric suffix ("judul-2", "judul-3", ...) when another article has, or
+// had, that slug. Each candidate is checked on its own, a long base is cut
+// to make room for the suffix.
+func UniqueArticleSlug(db *gorm.DB, base string, articleID uint) string {
+	if base == "" {
+		base = defaultSlug
+	}
+
+	slug := base
+	for n := 2; ArticleSlugTaken(db, slug, articleID); n++ {
+		suffix := fmt.Sprintf("-%v", n)
+		slug = utils.TruncateSlug(base, utils.MaxSlugLength-len(suffix)) + suffix
+	}
+
+	return slug
+}
+
+// RecordSlugChange keeps old in the slug history when the article moved to
+// another slug. The current slug is never part of the history.
+func (a *Article) RecordSlugChange(db *gorm.DB, old string) error {
+	if err := db.Where("slug = ?", a.Slug).Delete(&ArticleSlug{}).Error; err != nil {
+		return err
+	}
+
+	if old == "" || old == a.Slug {
+		return nil
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ArticleSlug{ArticleID: a.ID, Slug: old}).Error
+}
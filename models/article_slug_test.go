@@ -0,0 +1,44 @@
+package models
+
+import (
+	"final-project/utils"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUniqueArticleSlugOfLongBase(t *testing.T) {
+	db := testDB(t)
+	user := testUser(t, db, AUTHOR)
+
+	// a base of MaxSlugLength, so the suffixed candidates are cut at one of
+	// its hyphens and no longer start with it
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	base := suffix + strings.Repeat("-judul", 13)
+	base += "-" + strings.Repeat("a", utils.MaxSlugLength-len(base)-1)
+
+	want := []string{base}
+	for _, n := range []string{"-2", "-3", "-4"} {
+		want = append(want, utils.TruncateSlug(base, utils.MaxSlugLength-len(n))+n)
+	}
+
+	for i, w := range want {
+		slug := UniqueArticleSlug(db, base, 0)
+		if slug != w {
+			t.Fatalf("slug %v = %v, want %v", i+1, slug, w)
+		}
+
+		article := Article{
+			Title:     fmt.Sprintf("slug %v %v", i, suffix),
+			Slug:      slug,
+			UserID:    user.ID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := db.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { article.Delete(db) })
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// countQueries counts the SELECT statements db runs, those of preloads
+// included, into n until the test ends.
+func countQueries(t *testing.T, db *gorm.DB, n *int) {
+	err := db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		*n++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Callback().Query().Remove("test:count_queries") })
+}
+
+func TestArticleDetailsQueryCount(t *testing.T) {
+	db := testDB(t)
+	suffix := fmt.Sprint(time.Now().UnixNano())
+
+	category := Category{Name: "category-" + suffix}
+	tag := Tag{Name: "tag-" + suffix}
+	for _, row := range []interface{}{&category, &tag} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Delete(&tag)
+		db.Delete(&category)
+	})
+
+	ids := []uint{}
+	queries := map[int]int{}
+
+	n := 0
+	countQueries(t, db, &n)
+
+	for i := 1; i <= 5; i++ {
+		// every article has its own author, category and tag rows
+		user := testUser(t, db, AUTHOR)
+		article := Article{
+			Title:     fmt.Sprintf("query count %v %v", i, suffix),
+			Slug:      fmt.Sprintf("query-count-%v-%v", i, suffix),
+			Content:   "content",
+			UserID:    user.ID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := db.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { article.Delete(db) })
+
+		if err := article.InsertCategories(db, []uint{category.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := article.InsertTags(db, []uint{tag.ID}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, article.ID)
+
+		n = 0
+		var articles []Article
+		if err := db.Scopes(ArticleDetails).Where("id IN ?", ids).Find(&articles).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if len(articles) != i || articles[0].User.ID == 0 || len(articles[0].Categories) != 1 || articles[0].Tags[0].Tag.ID != tag.ID {
+			t.Fatalf("details of %v articles not loaded: %+v", i, articles)
+		}
+
+		queries[i] = n
+	}
+
+	// articles, users, article_categories, categories, article_tags, tags
+	for i, n := range queries {
+		if n != 6 {
+			t.Errorf("%v queries for %v articles, want 6", n, i)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audited actions.
+const (
+	AuditRoleChanged  = "user.role_changed"
+	AuditUserLocked   = "user.locked"
+	AuditUserUnlocked = "user.unlocked"
+	AuditTOTPEnabled  = "user.2fa_enabled"
+	AuditTOTPDisabled = "user.2fa_disabled"
+)
+
+// AuditDetails are the specifics of an audited action, e.g. the old and new
+// role of a role change. It is stored as a jsonb column.
+type AuditDetails map[string]string
+
+func (d AuditDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(d)
+	return string(b), err
+}
+
+func (d *AuditDetails) Scan(value interface{}) error {
+	var b []byte
+
+	switch value := value.(type) {
+	case nil:
+		*d = AuditDetails{}
+		return nil
+	case []byte:
+		b = value
+	case string:
+		b = []byte(value)
+	default:
+		return errors.New("details: unsupported value type")
+	}
+
+	return json.Unmarshal(b, d)
+}
+
+// AuditLog records a sensitive action. Entries are never updated or
+// deleted, and outlive the users they mention.
+type AuditLog struct {
+	ID uint `gorm:"primary_key;auto_increment" json:"id"`
+	// ActorID is the user who acted, nil for the system, e.g. the admin
+	// bootstrap.
+	ActorID    *uint        `gorm:"index" json:"actor_id"`
+	Action     string       `gorm:"size:50;not null;index" json:"action"`
+	TargetType string       `gorm:"size:50;not null" json:"target_type"`
+	TargetID   uint         `gorm:"not null;index" json:"target_id"`
+	Details    AuditDetails `gorm:"type:jsonb;not null;default:'{}'" json:"details"`
+	IP         string       `gorm:"size:45" json:"ip"`
+	CreatedAt  time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (l *AuditLog) BeforeUpdate(_ *gorm.DB) error {
+	return errors.New("audit logs are immutable")
+}
+
+func (l *AuditLog) BeforeDelete(_ *gorm.DB) error {
+	return errors.New("audit logs are immutable")
+}
+
+// RecordRoleChange audits the role of user changing from old, empty for new
+// users, to user.Role.
+func RecordRoleChange(db *gorm.DB, actorID *uint, ip string, user *User, old UserRole) error {
+	return recordUserAction(db, actorID, ip, AuditRoleChanged, user, AuditDetails{"from": string(old), "to": string(user.Role)})
+}
+
+// RecordLockout audits user being locked out for lockout after failed
+// logins from ip.
+func RecordLockout(db *gorm.DB, ip string, user *User, lockout time.Duration) error {
+	return recordUserAction(db, nil, ip, AuditUserLocked, user, AuditDetails{"duration": lockout.String()})
+}
+
+// RecordUnlock audits the lockout of user being lifted by actorID.
+func RecordUnlock(db *gorm.DB, actorID *uint, ip string, user *User) error {
+	return recordUserAction(db, actorID, ip, AuditUserUnlocked, user, AuditDetails{})
+}
+
+// RecordTOTPChange audits two factor authentication of user being enabled
+// or disabled, as action, by actorID.
+func RecordTOTPChange(db *gorm.DB, actorID *uint, ip string, action string, user *User) error {
+	return recordUserAction(db, actorID, ip, action, user, AuditDetails{})
+}
+
+func recordUserAction(db *gorm.DB, actorID *uint, ip string, action string, user *User, details AuditDetails) error {
+	return db.Create(&AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    details,
+		IP:         ip,
+		CreatedAt:  time.Now(),
+	}).Error
+}
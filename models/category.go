@@ -1,7 +1,10 @@
 package models
 
 import (
+	"final-project/utils"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Category struct {
@@ -12,11 +15,14 @@ type Category struct {
 	Article   []Article `gorm:"many2many" json:"-"`
 }
 
-func (ca *Category) Validate() []string {
-	errs := []string{}
+// Validate checks that the name is free.
+func (ca *Category) Validate(db *gorm.DB) utils.FieldErrors {
+	errs := utils.FieldErrors{}
+	var count int64
 
-	if ca.Name == "" {
-		errs = append(errs, "Nama kategori harus diisi")
+	db.Model(&Category{}).Where("name = ? AND id <> ?", ca.Name, ca.ID).Count(&count)
+	if count > 0 {
+		errs = append(errs, utils.NewFieldError("name", "unique", ""))
 	}
 
 	return errs
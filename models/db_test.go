@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the database of TEST_DATABASE_DSN, which the tests
+// write to, so it must not hold real data. Tests needing it are skipped
+// without it.
+func testDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(
+		&User{},
+		&Category{},
+		&Tag{},
+		&Article{},
+		&ArticleTag{},
+		&ArticleCategory{},
+		&ArticleRevision{},
+		&Upload{},
+		&ArticleSlug{},
+		&RecoveryCode{},
+		&UserIdentity{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// testUser creates a user of role, deleted with its rows when the test
+// ends.
+func testUser(t *testing.T, db *gorm.DB, role UserRole) *User {
+	user := User{
+		Name:     "test",
+		Email:    fmt.Sprintf("test-%v@example.com", time.Now().UnixNano()),
+		Password: "-",
+		Role:     role,
+	}
+
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Where("user_id = ?", user.ID).Delete(&RecoveryCode{})
+		db.Delete(&user)
+	})
+
+	return &user
+}
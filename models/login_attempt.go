@@ -0,0 +1,152 @@
+package models
+
+import (
+	"final-project/apperrors"
+	"final-project/utils"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginAttempt counts the failed logins of an email or an IP, see
+// emailLoginKey and ipLoginKey. Emails are counted whether or not they are
+// registered, so lockouts don't tell which are.
+type LoginAttempt struct {
+	Key          string     `gorm:"primary_key;size:150" json:"key"`
+	Failures     int        `gorm:"not null;default:0" json:"failures"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	LastFailedAt time.Time  `gorm:"not null" json:"last_failed_at"`
+}
+
+// LoginLockedError is returned by LoginCheck while the email or the IP is
+// locked out.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "login locked for " + e.RetryAfter.String()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return apperrors.TooManyRequests("LOGIN_LOCKED", "seconds", strconv.Itoa(e.Seconds()))
+}
+
+// Seconds is RetryAfter rounded up, as sent in the Retry-After header.
+func (e *LoginLockedError) Seconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+func emailLoginKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLock returns a LoginLockedError if any of keys is locked out.
+func checkLoginLock(db *gorm.DB, keys ...string) error {
+	var attempts []LoginAttempt
+
+	if err := db.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+	for _, attempt := range attempts {
+		if d := time.Until(*attempt.LockedUntil); d > retryAfter {
+			retryAfter = d
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts a failed login for key and locks it out once
+// it failed attempts times in a row, see utils.LoginLimit. It returns the
+// lockout, zero if key is not locked out.
+func recordLoginFailure(db *gorm.DB, key string, attempts int, limit utils.LoginLimit) (time.Duration, error) {
+	now := time.Now()
+	attempt := LoginAttempt{}
+
+	// the counter restarts after MaxLockout without failure nor lockout
+	err := db.Raw(`INSERT INTO login_attempts (key, failures, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN GREATEST(login_attempts.last_failed_at, COALESCE(login_attempts.locked_until, login_attempts.last_failed_at)) < ?
+				THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING *`, key, now, now.Add(-limit.MaxLockout)).Scan(&attempt).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	lockout := limit.LockoutAfter(attempt.Failures, attempts)
+	if lockout == 0 {
+		return 0, nil
+	}
+
+	err = db.Model(&LoginAttempt{}).Where("key = ?", key).Update("locked_until", now.Add(lockout)).Error
+
+	return lockout, err
+}
+
+// loginFailed counts a failed login of email from ip and returns failure,
+// or a LoginLockedError if this failure locked it out. user is the owner of
+// email, nil if it is not registered. Lockouts are logged, and audited for
+// registered users.
+func loginFailed(db *gorm.DB, email string, ip string, user *User, failure error) error {
+	limit, err := utils.LoginLimits()
+	if err != nil {
+		return err
+	}
+
+	ipLockout, err := recordLoginFailure(db, ipLoginKey(ip), limit.IPAttempts, limit)
+	if err != nil {
+		return err
+	}
+	if ipLockout > 0 {
+		log.Printf("login: ip %v locked out for %v", ip, ipLockout)
+	}
+
+	emailLockout, err := recordLoginFailure(db, emailLoginKey(email), limit.AccountAttempts, limit)
+	if err != nil {
+		return err
+	}
+	if emailLockout > 0 {
+		log.Printf("login: %v locked out for %v", email, emailLockout)
+
+		if user != nil {
+			if err := RecordLockout(db, ip, user, emailLockout); err != nil {
+				return err
+			}
+		}
+	}
+
+	lockout := emailLockout
+	if ipLockout > lockout {
+		lockout = ipLockout
+	}
+
+	if lockout > 0 {
+		return &LoginLockedError{RetryAfter: lockout}
+	}
+
+	return failure
+}
+
+// Unlock lifts the lockout of u and forgets its failed logins. Lockouts of
+// the IPs it logged in from stay.
+func (u *User) Unlock(db *gorm.DB) error {
+	return db.Where("key = ?", emailLoginKey(u.Email)).Delete(&LoginAttempt{}).Error
+}
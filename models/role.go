@@ -0,0 +1,113 @@
+package models
+
+import (
+	"final-project/utils"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+type Permission string
+
+const (
+	// create articles and edit the ones written by the user
+	PermArticleWrite Permission = "article:write"
+	// edit, delete and read the drafts of anyone's articles
+	PermArticleManage   Permission = "article:manage"
+	PermArticlePublish  Permission = "article:publish"
+	PermCategoryManage  Permission = "category:manage"
+	PermTagManage       Permission = "tag:manage"
+	PermCommentModerate Permission = "comment:moderate"
+	PermUserManage      Permission = "user:manage"
+	// edit the profile of the user and post or delete its own comments,
+	// every role may, API keys only with these scopes
+	PermProfileWrite Permission = "profile:write"
+	PermCommentWrite Permission = "comment:write"
+)
+
+var AllPermissions = []Permission{
+	PermArticleWrite,
+	PermArticleManage,
+	PermArticlePublish,
+	PermCategoryManage,
+	PermTagManage,
+	PermCommentModerate,
+	PermUserManage,
+	PermProfileWrite,
+	PermCommentWrite,
+}
+
+// rolePermissions maps each role to what it may do. ADMIN may do anything.
+var rolePermissions = map[UserRole][]Permission{
+	EDITOR: {
+		PermArticleWrite,
+		PermArticleManage,
+		PermArticlePublish,
+		PermCategoryManage,
+		PermTagManage,
+		PermCommentModerate,
+		PermProfileWrite,
+		PermCommentWrite,
+	},
+	AUTHOR:    {PermArticleWrite, PermProfileWrite, PermCommentWrite},
+	MODERATOR: {PermCommentModerate, PermProfileWrite, PermCommentWrite},
+	READER:    {PermProfileWrite, PermCommentWrite},
+}
+
+func parseRoles(list string) []UserRole {
+	roles := []UserRole{}
+	for _, role := range strings.Split(list, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, UserRole(role))
+		}
+	}
+
+	return roles
+}
+
+// RequiresTOTP reports whether r is one of TOTP_REQUIRED_ROLES, which must
+// use two factor authentication to use their permissions, see User.Can.
+func (r UserRole) RequiresTOTP() bool {
+	return slices.Contains(parseRoles(utils.TOTPRequiredRoles()), r)
+}
+
+func (r UserRole) Can(p Permission) bool {
+	if r == ADMIN {
+		return true
+	}
+
+	return slices.Contains(rolePermissions[r], p)
+}
+
+// Can reports whether the user, nil for anonymous requests, has permission
+// p. Users whose role requires two factor authentication are readers until
+// they enable it. Users authenticated by an API key are also limited to its
+// scopes.
+func (u *User) Can(p Permission) bool {
+	if u == nil {
+		return false
+	}
+
+	if u.scoped && !slices.Contains(u.scopes, p) {
+		return false
+	}
+
+	if u.Role.RequiresTOTP() && !u.TOTPEnabled() {
+		return READER.Can(p)
+	}
+
+	return u.Role.Can(p)
+}
+
+// RestrictTo limits the permissions of u to scopes, for requests made with
+// an API key.
+func (u *User) RestrictTo(scopes []Permission) {
+	u.scoped = true
+	u.scopes = scopes
+}
+
+// IsRestricted reports whether u is authenticated by an API key, see
+// RestrictTo.
+func (u *User) IsRestricted() bool {
+	return u.scoped
+}
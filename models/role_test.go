@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestUserCanWithAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		role   UserRole
+		scopes []Permission
+		perm   Permission
+		can    bool
+	}{
+		{READER, nil, PermProfileWrite, false},
+		{READER, nil, PermCommentWrite, false},
+		{READER, []Permission{PermCommentWrite}, PermCommentWrite, true},
+		{READER, []Permission{PermCommentWrite}, PermProfileWrite, false},
+		{AUTHOR, []Permission{PermArticleWrite}, PermArticleWrite, true},
+		{AUTHOR, []Permission{PermArticleWrite}, PermCommentWrite, false},
+		// scopes don't add to the role
+		{READER, []Permission{PermArticleWrite}, PermArticleWrite, false},
+	}
+
+	for _, tt := range tests {
+		user := User{Role: tt.role}
+
+		if !user.Can(PermProfileWrite) || !user.Can(PermCommentWrite) {
+			t.Errorf("%v can't write its profile and comments with a session", tt.role)
+		}
+
+		user.RestrictTo(tt.scopes)
+		if got := user.Can(tt.perm); got != tt.can {
+			t.Errorf("%v with scopes %v: Can(%v) = %v, want %v", tt.role, tt.scopes, tt.perm, got, tt.can)
+		}
+	}
+}
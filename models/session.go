@@ -0,0 +1,96 @@
+package models
+
+import (
+	"final-project/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Session struct {
+	ID               uint       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	User             User       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type AuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
+
+// Issue rotates the refresh token of the session and returns a fresh token
+// pair. A new session is created when s has no ID yet.
+func (s *Session) Issue(db *gorm.DB) (*AuthToken, error) {
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshLife, err := utils.RefreshTokenLifespan()
+	if err != nil {
+		return nil, err
+	}
+
+	accessLife, err := utils.AccessTokenLifespan()
+	if err != nil {
+		return nil, err
+	}
+
+	s.RefreshTokenHash = utils.HashToken(refreshToken)
+	s.ExpiresAt = time.Now().Add(refreshLife)
+	s.UpdatedAt = time.Now()
+
+	if s.ID == 0 {
+		s.CreatedAt = time.Now()
+		if err := db.Create(s).Error; err != nil {
+			return nil, err
+		}
+	} else if err := db.Model(s).Updates(Session{
+		RefreshTokenHash: s.RefreshTokenHash,
+		ExpiresAt:        s.ExpiresAt,
+		UpdatedAt:        s.UpdatedAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateToken(s.UserID, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthToken{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessLife.Seconds()),
+	}, nil
+}
+
+func (s *Session) Revoke(db *gorm.DB) error {
+	now := time.Now()
+	s.RevokedAt = &now
+	return db.Model(s).Update("revoked_at", now).Error
+}
+
+func FindSessionByRefreshToken(db *gorm.DB, refreshToken string) (Session, error) {
+	session := Session{}
+	err := db.Where("refresh_token_hash=?", utils.HashToken(refreshToken)).First(&session).Error
+	return session, err
+}
+
+func RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&Session{}).
+		Where("user_id=? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
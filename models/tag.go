@@ -1,7 +1,10 @@
 package models
 
 import (
+	"final-project/utils"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Tag struct {
@@ -12,11 +15,14 @@ type Tag struct {
 	Article   []Article `gorm:"many2many" json:"-"`
 }
 
-func (t *Tag) Validate() []string {
-	errs := []string{}
+// Validate checks that the name is free.
+func (t *Tag) Validate(db *gorm.DB) utils.FieldErrors {
+	errs := utils.FieldErrors{}
+	var count int64
 
-	if t.Name == "" {
-		errs = append(errs, "Nama tag harus diisi")
+	db.Model(&Tag{}).Where("name = ? AND id <> ?", t.Name, t.ID).Count(&count)
+	if count > 0 {
+		errs = append(errs, utils.NewFieldError("name", "unique", ""))
 	}
 
 	return errs
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"final-project/apperrors"
+	"final-project/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at once.
+const recoveryCodeCount = 10
+
+var (
+	// ErrTOTPRequired is returned by LoginCheck for a right password when
+	// the user has two factor authentication and sent no code.
+	ErrTOTPRequired       = apperrors.Unauthorized("TOTP_REQUIRED")
+	ErrInvalidOTP         = apperrors.Unauthorized("INVALID_OTP")
+	ErrTOTPAlreadyEnabled = apperrors.Conflict("TOTP_ALREADY_ENABLED")
+	ErrTOTPNotEnrolled    = apperrors.Conflict("TOTP_NOT_ENROLLED")
+	ErrTOTPMandatory      = apperrors.Forbidden("TOTP_MANDATORY")
+)
+
+// RecoveryCode signs a user in once when its authenticator is lost. Only
+// its hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	User      User       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TOTPEnrollment is what an authenticator app needs to generate codes.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI of the secret, to show as a QR code
+	URI string `json:"uri"`
+}
+
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// EnrollTOTP gives u a new TOTP secret. Two factor authentication is only
+// enabled once a code of it is confirmed, see ConfirmTOTP.
+func (u *User) EnrollTOTP(db *gorm.DB) (*TOTPEnrollment, error) {
+	if u.TOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(u).UpdateColumns(map[string]interface{}{"totp_secret": encrypted, "totp_last_step": 0}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{Secret: secret, URI: utils.TOTPURI(secret, u.Email)}, nil
+}
+
+// ConfirmTOTP enables two factor authentication for u if code is valid for
+// the enrolled secret at now, and returns its recovery codes.
+func (u *User) ConfirmTOTP(db *gorm.DB, code string, now time.Time) ([]string, error) {
+	if u.TOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	ok, err := u.checkTOTP(db, code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidOTP
+	}
+
+	if err := db.Model(u).UpdateColumn("totp_enabled_at", now).Error; err != nil {
+		return nil, err
+	}
+	u.TOTPEnabledAt = &now
+
+	return u.RegenerateRecoveryCodes(db)
+}
+
+// VerifySecondFactor checks otp is either a TOTP code of u at now or one of
+// its unused recovery codes. Either can only be used once.
+func (u *User) VerifySecondFactor(db *gorm.DB, otp string, now time.Time) error {
+	if otp == "" {
+		return ErrTOTPRequired
+	}
+
+	ok, err := u.checkTOTP(db, otp, now)
+	if err != nil || ok {
+		return err
+	}
+
+	ok, err = u.useRecoveryCode(db, otp, now)
+	if err != nil || ok {
+		return err
+	}
+
+	return ErrInvalidOTP
+}
+
+// checkTOTP validates code and uses up its time step. The step is claimed
+// in the database, so a code can't be used twice even concurrently.
+func (u *User) checkTOTP(db *gorm.DB, code string, now time.Time) (bool, error) {
+	if u.TOTPSecret == "" {
+		return false, ErrTOTPNotEnrolled
+	}
+
+	secret, err := utils.DecryptSecret(u.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, now, u.TOTPLastStep)
+	if !ok {
+		return false, nil
+	}
+
+	res := db.Model(&User{}).Where("id = ? AND totp_last_step < ?", u.ID, step).UpdateColumn("totp_last_step", step)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	u.TOTPLastStep = step
+
+	return true, nil
+}
+
+func (u *User) useRecoveryCode(db *gorm.DB, code string, now time.Time) (bool, error) {
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+
+	res := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", u.ID, hash).
+		UpdateColumn("used_at", now)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of u. The codes are
+// only returned here, they can't be read again.
+func (u *User) RegenerateRecoveryCodes(db *gorm.DB) ([]string, error) {
+	if err := db.Where("user_id = ?", u.ID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code
+		records[i] = RecoveryCode{
+			UserID:    u.ID,
+			CodeHash:  utils.HashToken(utils.NormalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		}
+	}
+
+	if err := db.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns two factor authentication off for u and forgets its
+// secret and recovery codes.
+func (u *User) DisableTOTP(db *gorm.DB) error {
+	err := db.Model(u).UpdateColumns(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	u.TOTPSecret = ""
+	u.TOTPEnabledAt = nil
+	u.TOTPLastStep = 0
+
+	return db.Where("user_id = ?", u.ID).Delete(&RecoveryCode{}).Error
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"final-project/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// enrolledUser returns a user with two factor authentication enabled at
+// now, its secret and its recovery codes.
+func enrolledUser(t *testing.T, now time.Time) (*User, string, []string) {
+	db := testDB(t)
+	user := testUser(t, db, EDITOR)
+
+	enrollment, err := user.EnrollTOTP(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.First(user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	code, _ := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(now))
+	recovery, err := user.ConfirmTOTP(db, code, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, enrollment.Secret, recovery
+}
+
+func TestVerifySecondFactorReplay(t *testing.T) {
+	db := testDB(t)
+	now := time.Unix(1234567890, 0)
+	user, secret, _ := enrolledUser(t, now)
+
+	// ConfirmTOTP used the code of now
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(now))
+	if err := user.VerifySecondFactor(db, code, now); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("replayed code: err = %v, want %v", err, ErrInvalidOTP)
+	}
+
+	next, _ := utils.TOTPCode(secret, utils.TOTPStep(now)+1)
+	stale := *user
+
+	if err := user.VerifySecondFactor(db, next, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("next code: %v", err)
+	}
+
+	// a copy loaded before the code was used is still refused by the database
+	if err := stale.VerifySecondFactor(db, next, now.Add(30*time.Second)); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("code replayed concurrently: err = %v, want %v", err, ErrInvalidOTP)
+	}
+
+	stored := User{}
+	db.First(&stored, user.ID)
+	if stored.TOTPLastStep != utils.TOTPStep(now)+1 {
+		t.Errorf("totp_last_step = %v, want %v", stored.TOTPLastStep, utils.TOTPStep(now)+1)
+	}
+}
+
+func TestVerifySecondFactorRecoveryCode(t *testing.T) {
+	db := testDB(t)
+	now := time.Unix(1234567890, 0)
+	user, _, codes := enrolledUser(t, now)
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%v recovery codes, want %v", len(codes), recoveryCodeCount)
+	}
+
+	var stored []RecoveryCode
+	db.Where("user_id = ?", user.ID).Find(&stored)
+	for _, record := range stored {
+		for _, code := range codes {
+			if strings.Contains(record.CodeHash, utils.NormalizeRecoveryCode(code)) {
+				t.Fatalf("recovery code %v is stored in clear", code)
+			}
+		}
+	}
+
+	if err := user.VerifySecondFactor(db, strings.ToUpper(codes[0]), now); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+
+	if err := user.VerifySecondFactor(db, codes[0], now); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("reused recovery code: err = %v, want %v", err, ErrInvalidOTP)
+	}
+
+	if err := user.VerifySecondFactor(db, codes[1], now); err != nil {
+		t.Errorf("other recovery code: %v", err)
+	}
+
+	// regenerating revokes the unused codes
+	if _, err := user.RegenerateRecoveryCodes(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := user.VerifySecondFactor(db, codes[2], now); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("replaced recovery code: err = %v, want %v", err, ErrInvalidOTP)
+	}
+}
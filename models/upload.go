@@ -0,0 +1,137 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"final-project/storage"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// files is the storage of uploads, see SetFileStorage.
+var files storage.Storage
+
+// SetFileStorage sets the storage uploads are kept in. The URLs of its files
+// are stored as they don't expire, and signed when sent if its objects are
+// private, see FileURL.
+func SetFileStorage(store storage.Storage) {
+	files = store
+}
+
+// FileURL returns the stored url as clients can download it, see
+// storage.Storage.ReadURL.
+func FileURL(url string) string {
+	if files == nil || url == "" {
+		return url
+	}
+
+	signed, err := files.ReadURL(url)
+	if err != nil {
+		log.Printf("signing %v: %v", url, err)
+		return url
+	}
+
+	return signed
+}
+
+// StoredFileURL returns the url a client sent as it is stored, without the
+// signature FileURL may have added.
+func StoredFileURL(url string) string {
+	if files == nil || url == "" {
+		return url
+	}
+
+	return files.StoredURL(url)
+}
+
+type ImageVariant struct {
+	URL    string `json:"url"`
+	WebP   string `json:"webp,omitempty"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ImageVariants maps a variant name (thumbnail, card, og) to its files, it
+// is stored as a jsonb column.
+type ImageVariants map[string]ImageVariant
+
+// readable returns the variants with URLs clients can download, see FileURL.
+func (v ImageVariants) readable() ImageVariants {
+	readable := ImageVariants{}
+	for name, variant := range v {
+		variant.URL = FileURL(variant.URL)
+		variant.WebP = FileURL(variant.WebP)
+		readable[name] = variant
+	}
+
+	return readable
+}
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (v *ImageVariants) Scan(value interface{}) error {
+	var b []byte
+
+	switch value := value.(type) {
+	case nil:
+		*v = ImageVariants{}
+		return nil
+	case []byte:
+		b = value
+	case string:
+		b = []byte(value)
+	default:
+		return errors.New("images: unsupported value type")
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// Upload is an image uploaded through the API and its resized variants. URL
+// and the URLs of the variants are stored as returned by
+// storage.Storage.URL.
+type Upload struct {
+	ID          uint          `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint          `gorm:"not null;index" json:"user_id"`
+	Key         string        `gorm:"size:255;not null;unique" json:"-"`
+	URL         string        `gorm:"size:1024;not null;index" json:"url"`
+	ContentType string        `gorm:"size:50;not null" json:"content_type"`
+	Size        int64         `gorm:"not null" json:"size"`
+	Width       int           `gorm:"not null" json:"width"`
+	Height      int           `gorm:"not null" json:"height"`
+	Variants    ImageVariants `gorm:"type:jsonb;not null;default:'{}'" json:"images"`
+	CreatedAt   time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	User        User          `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (u Upload) MarshalJSON() ([]byte, error) {
+	type upload Upload
+
+	readable := upload(u)
+	readable.URL = FileURL(u.URL)
+	readable.Variants = u.Variants.readable()
+
+	return json.Marshal(readable)
+}
+
+// FindImageVariants returns the variants of the upload served at url, or
+// none when url is not an uploaded image.
+func FindImageVariants(db *gorm.DB, url string) ImageVariants {
+	upload := Upload{}
+
+	if url == "" || db.Where("url = ?", url).Limit(1).Find(&upload).Error != nil || upload.ID == 0 {
+		return ImageVariants{}
+	}
+
+	return upload.Variants
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"final-project/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileURLsAreSignedWhenSent(t *testing.T) {
+	store := &storage.S3{
+		Endpoint:  "http://localhost:9000",
+		Region:    "us-east-1",
+		Bucket:    "blog",
+		PathStyle: true,
+		Private:   true,
+		URLExpiry: time.Hour,
+	}
+	SetFileStorage(store)
+	t.Cleanup(func() { SetFileStorage(nil) })
+
+	stored, _ := store.URL("images/a.png")
+	variant, _ := store.URL("images/a-card.jpg")
+
+	article := Article{
+		ImageUrl: stored,
+		Images:   ImageVariants{"card": {URL: variant, Width: 800, Height: 450}},
+	}
+
+	b, err := json.Marshal(article)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := struct {
+		ImageUrl string        `json:"image_url"`
+		Images   ImageVariants `json:"images"`
+	}{}
+	json.Unmarshal(b, &sent)
+
+	if !strings.HasPrefix(sent.ImageUrl, stored+"?") || !strings.HasPrefix(sent.Images["card"].URL, variant+"?") {
+		t.Errorf("sent unsigned urls %v %v", sent.ImageUrl, sent.Images["card"].URL)
+	}
+
+	if StoredFileURL(sent.ImageUrl) != stored {
+		t.Errorf("StoredFileURL(%v) = %v, want %v", sent.ImageUrl, StoredFileURL(sent.ImageUrl), stored)
+	}
+
+	// the stored value keeps the unsigned urls
+	value, _ := article.Images.Value()
+	if strings.Contains(value.(string), "X-Amz-") {
+		t.Errorf("stored variants %v are signed", value)
+	}
+}
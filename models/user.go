@@ -1,9 +1,11 @@
 package models
 
 import (
+	"errors"
+	"final-project/apperrors"
 	"final-project/utils"
 	"fmt"
-	"regexp"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -12,19 +14,39 @@ import (
 
 type UserRole string
 
+// The roles of a user, see rolePermissions for what each may do. READER,
+// the lowest, was called "user" before roles were introduced.
 const (
-	ADMIN UserRole = "admin"
-	USER  UserRole = "user"
+	ADMIN     UserRole = "admin"
+	EDITOR    UserRole = "editor"
+	AUTHOR    UserRole = "author"
+	MODERATOR UserRole = "moderator"
+	READER    UserRole = "reader"
 )
 
+// User is never sent as is, see MarshalJSON.
 type User struct {
-	ID        uint      `gorm:"primary_key;auto_increment" json:"id"`
-	Name      string    `gorm:"size:255;not null" json:"name"`
-	Email     string    `gorm:"size:100;not null;unique" json:"email"`
-	Password  string    `gorm:"size:100;not null" json:"password"`
-	Role      UserRole  `sql:"type:ENUM('admin', 'user')" json:"role"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID              uint       `gorm:"primary_key;auto_increment" json:"id"`
+	Name            string     `gorm:"size:255;not null" json:"name"`
+	Email           string     `gorm:"size:100;not null;unique" json:"email"`
+	Password        string     `gorm:"size:100;not null" json:"-"`
+	AvatarUrl       string     `gorm:"size:255" json:"avatar_url"`
+	Bio             string     `gorm:"size:500" json:"bio"`
+	Role            UserRole   `gorm:"size:20;default:reader" json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// TOTPSecret is encrypted, see utils.EncryptSecret. It is set by an
+	// enrollment but only used once TOTPEnabledAt is set.
+	TOTPSecret    string     `gorm:"column:totp_secret;size:255" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"-"`
+	// TOTPLastStep is the time step of the last accepted code, see
+	// utils.ValidateTOTP.
+	TOTPLastStep int64     `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	// scoped and scopes limit the permissions of a user authenticated by an
+	// API key, see RestrictTo.
+	scoped bool
+	scopes []Permission
 }
 
 func Hash(password string) ([]byte, error) {
@@ -36,31 +58,18 @@ func VerifyPassword(hashPw, pw string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashPw), []byte(pw))
 }
 
-func isEmailValid(e string) bool {
-	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-	return emailRegex.MatchString(e)
-}
-
-func (u *User) Validate(db *gorm.DB) []string {
-	errors := []string{}
-
-	if err := db.Where("email=?", u.Email).First(&u).Error; err == nil {
-		errors = append(errors, "Email sudah digunakan")
-	}
-
-	if !isEmailValid(u.Email) {
-		errors = append(errors, "Email tidak valid")
-	}
-
-	if len(u.Password) < 8 {
-		errors = append(errors, "Password harus lebih dari 8 karakter")
-	}
+// Validate checks what the binding tags of the input can't: that the email
+// is free.
+func (u *User) Validate(db *gorm.DB) utils.FieldErrors {
+	errs := utils.FieldErrors{}
+	var count int64
 
-	if u.Role != "admin" && u.Role != "user" {
-		errors = append(errors, "Role harus 'admin' atau 'user'")
+	db.Model(&User{}).Where("email = ? AND id <> ?", u.Email, u.ID).Count(&count)
+	if count > 0 {
+		errs = append(errs, utils.NewFieldError("email", "unique", ""))
 	}
 
-	return errors
+	return errs
 }
 
 func (u *User) BeforeSave(_ *gorm.DB, inputPw string) error {
@@ -74,22 +83,62 @@ func (u *User) BeforeSave(_ *gorm.DB, inputPw string) error {
 	return nil
 }
 
-func (u *User) LoginCheck(db *gorm.DB) (string, error) {
+// ErrInvalidCredentials is returned by LoginCheck for an unknown email as
+// well as a wrong password, so clients can't probe for registered emails.
+var ErrInvalidCredentials = apperrors.Unauthorized("INVALID_CREDENTIALS")
+
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// compareDummyPassword takes as long as checking a password, so unknown
+// emails can't be told apart by response time either.
+func compareDummyPassword(pw string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(pw))
+}
+
+// LoginCheck signs u in with its email and password from ip. otp is the
+// second factor, required when the user enabled two factor authentication.
+// Failed logins are counted per email and per IP, both are locked out for a
+// while after too many, see loginFailed.
+func (u *User) LoginCheck(db *gorm.DB, ip string, otp string) (*AuthToken, error) {
+	if err := checkLoginLock(db, emailLoginKey(u.Email), ipLoginKey(ip)); err != nil {
+		return nil, err
+	}
+
 	user := User{}
 
 	if err := db.Model(User{}).Where("email=?", u.Email).Take(&user).Error; err != nil {
-		return "", err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			compareDummyPassword(u.Password)
+			return nil, loginFailed(db, u.Email, ip, nil, ErrInvalidCredentials)
+		}
+		return nil, err
 	}
 
 	if err := VerifyPassword(user.Password, u.Password); err != nil {
-		return "", err
+		return nil, loginFailed(db, u.Email, ip, &user, ErrInvalidCredentials)
 	}
 
-	token, err := utils.GenerateToken(user.ID)
+	if user.TOTPEnabled() {
+		if err := user.VerifySecondFactor(db, otp, time.Now()); err != nil {
+			if errors.Is(err, ErrInvalidOTP) {
+				return nil, loginFailed(db, u.Email, ip, &user, ErrInvalidOTP)
+			}
+			return nil, err
+		}
+	}
 
-	if err != nil {
-		return "", err
+	if err := user.Unlock(db); err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	session := Session{UserID: user.ID}
+
+	return session.Issue(db)
 }
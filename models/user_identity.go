@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"final-project/apperrors"
+	"final-project/oidc"
+	"final-project/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOIDCEmailRequired = apperrors.BadRequest("OIDC_EMAIL_REQUIRED")
+	// ErrOIDCEmailTaken is returned when the email of an identity belongs to
+	// a user, but the provider or the user did not verify it, so it can't be
+	// linked. Whoever registered an email they don't own would otherwise
+	// share the account with its owner.
+	ErrOIDCEmailTaken = apperrors.Conflict("OIDC_EMAIL_TAKEN")
+	// ErrOIDCTOTPEnabled is returned for users with two factor
+	// authentication, the provider can't check their second factor.
+	ErrOIDCTOTPEnabled = apperrors.Forbidden("OIDC_TOTP_ENABLED")
+	// ErrOIDCLinkPrivileged is returned when the email of an identity
+	// belongs to a user who may publish or manage users. Whoever controls
+	// the email at the provider would take the account over.
+	ErrOIDCLinkPrivileged = apperrors.Forbidden("OIDC_LINK_PRIVILEGED")
+)
+
+// UserIdentity links a user to its account at an identity provider.
+type UserIdentity struct {
+	ID        uint      `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_user_identity" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identity" json:"subject"`
+	Email     string    `gorm:"size:100" json:"email"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SignInWithIdentity returns the user identity of provider is linked to.
+// Unknown identities are linked to the user with their email if both the
+// provider and the user verified it and the user is not privileged, see
+// ErrOIDCEmailTaken and ErrOIDCLinkPrivileged, else a reader is created for
+// them.
+func SignInWithIdentity(db *gorm.DB, provider string, identity *oidc.Identity) (*User, error) {
+	linked := UserIdentity{}
+
+	err := db.Preload("User").Where("provider = ? AND subject = ?", provider, identity.Subject).Take(&linked).Error
+	if err == nil {
+		return signInLinkedUser(&linked.User)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, ErrOIDCEmailRequired
+	}
+
+	user := User{}
+
+	err = db.Where("email = ?", identity.Email).Take(&user).Error
+	switch {
+	case err == nil:
+		if !identity.EmailVerified || user.EmailVerifiedAt == nil {
+			return nil, ErrOIDCEmailTaken
+		}
+		if user.Role.Can(PermArticlePublish) || user.Role.Can(PermUserManage) {
+			return nil, ErrOIDCLinkPrivileged
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := provisionUser(db, identity, &user); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	// the provider vouches for the email of the created user
+	if identity.EmailVerified && user.EmailVerifiedAt == nil {
+		if err := user.MarkEmailVerified(db); err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.Create(&UserIdentity{
+		UserID:    user.ID,
+		Provider:  provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return signInLinkedUser(&user)
+}
+
+func signInLinkedUser(user *User) (*User, error) {
+	if user.TOTPEnabled() {
+		return nil, ErrOIDCTOTPEnabled
+	}
+
+	return user, nil
+}
+
+// provisionUser creates a reader for identity. Its password is random, a
+// password reset sets one.
+func provisionUser(db *gorm.DB, identity *oidc.Identity, user *User) error {
+	password, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	hash, err := Hash(password)
+	if err != nil {
+		return err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = strings.Split(identity.Email, "@")[0]
+	}
+
+	*user = User{
+		Name:     name,
+		Email:    identity.Email,
+		Password: string(hash),
+		Role:     READER,
+	}
+
+	if strings.HasPrefix(identity.Picture, "https://") {
+		user.AvatarUrl = identity.Picture
+	}
+
+	if len(user.AvatarUrl) > 255 {
+		user.AvatarUrl = ""
+	}
+
+	return db.Create(user).Error
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"final-project/oidc"
+	"fmt"
+	"testing"
+)
+
+func TestSignInWithIdentityLinksByVerifiedEmail(t *testing.T) {
+	db := testDB(t)
+
+	tests := []struct {
+		role UserRole
+		// verified by the provider, and by the local user
+		verified      bool
+		localVerified bool
+		err           error
+	}{
+		{READER, true, true, nil},
+		{AUTHOR, true, true, nil},
+		{READER, false, true, ErrOIDCEmailTaken},
+		// registered by someone who may not own the email
+		{READER, true, false, ErrOIDCEmailTaken},
+		{EDITOR, true, true, ErrOIDCLinkPrivileged},
+		{ADMIN, true, true, ErrOIDCLinkPrivileged},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v verified %v locally %v", tt.role, tt.verified, tt.localVerified), func(t *testing.T) {
+			user := testUser(t, db, tt.role)
+			if tt.localVerified {
+				if err := user.MarkEmailVerified(db); err != nil {
+					t.Fatal(err)
+				}
+			}
+			identity := &oidc.Identity{Subject: user.Email, Email: user.Email, EmailVerified: tt.verified}
+
+			signedIn, err := SignInWithIdentity(db, "mock", identity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			var links int64
+			db.Model(&UserIdentity{}).Where("user_id = ?", user.ID).Count(&links)
+
+			if tt.err != nil {
+				if links != 0 {
+					t.Errorf("identity was linked")
+				}
+				return
+			}
+
+			if signedIn.ID != user.ID || links != 1 {
+				t.Errorf("signed in user %v with %v links, want user %v with 1", signedIn.ID, links, user.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"crypto/subtle"
+	"errors"
+	"final-project/apperrors"
+	"final-project/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidActionToken = apperrors.BadRequest("INVALID_ACTION_TOKEN")
+
+// VerificationToken returns a token confirming u owns its current email.
+func (u *User) VerificationToken() (string, error) {
+	ttl, err := utils.VerifyTokenLifespan()
+	if err != nil {
+		return "", err
+	}
+
+	return utils.GenerateActionToken(utils.PurposeVerifyEmail, u.ID, u.actionStamp(utils.PurposeVerifyEmail), ttl)
+}
+
+// PasswordResetToken returns a token allowing to set a new password for u.
+// It is void once the password changed.
+func (u *User) PasswordResetToken() (string, error) {
+	ttl, err := utils.ResetTokenLifespan()
+	if err != nil {
+		return "", err
+	}
+
+	return utils.GenerateActionToken(utils.PurposeResetPassword, u.ID, u.actionStamp(utils.PurposeResetPassword), ttl)
+}
+
+// actionStamp ties the tokens of purpose to the state they act on, the
+// email to verify or the password to replace. Tokens are readable by their
+// holder, so the stamp is a truncated hash.
+func (u *User) actionStamp(purpose string) string {
+	value := u.Email
+	if purpose == utils.PurposeResetPassword {
+		value = u.Password
+	}
+
+	return utils.HashToken(purpose + ":" + value)[:16]
+}
+
+// FindUserByActionToken returns the user token was issued to for purpose,
+// if the state it was issued for did not change since.
+func FindUserByActionToken(db *gorm.DB, token string, purpose string) (*User, error) {
+	claims, err := utils.ParseActionToken(token, purpose)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	user := User{}
+
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidActionToken
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(user.actionStamp(purpose)), []byte(claims.Stamp)) != 1 {
+		return nil, ErrInvalidActionToken
+	}
+
+	return &user, nil
+}
+
+func (u *User) MarkEmailVerified(db *gorm.DB) error {
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+
+	return db.Model(u).UpdateColumn("email_verified_at", now).Error
+}
+
+// ResetPassword replaces the password of u and signs it out everywhere, as
+// whoever knew the old password may be signed in. Holding a reset token
+// proves u owns its email, which is verified as well.
+func (u *User) ResetPassword(db *gorm.DB, password string) error {
+	hash, err := Hash(password)
+	if err != nil {
+		return err
+	}
+
+	columns := map[string]interface{}{"password": string(hash), "updated_at": time.Now()}
+	if u.EmailVerifiedAt == nil {
+		columns["email_verified_at"] = time.Now()
+	}
+
+	if err := db.Model(u).UpdateColumns(columns).Error; err != nil {
+		return err
+	}
+
+	return RevokeUserSessions(db, u.ID)
+}
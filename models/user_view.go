@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Author is the public view of a user, as shown next to its articles,
+// comments and revisions.
+type Author struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	AvatarUrl string `json:"avatar_url"`
+	Bio       string `json:"bio"`
+}
+
+// UserProfile is the private view of a user, for the user itself and for
+// user managers. It still leaves out the password hash.
+type UserProfile struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	AvatarUrl       string     `json:"avatar_url"`
+	Bio             string     `json:"bio"`
+	Role            UserRole   `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabled     bool       `json:"two_factor_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (u User) Author() Author {
+	return Author{
+		ID:        u.ID,
+		Name:      u.Name,
+		AvatarUrl: FileURL(u.AvatarUrl),
+		Bio:       u.Bio,
+	}
+}
+
+func (u User) Profile() UserProfile {
+	return UserProfile{
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		AvatarUrl:       FileURL(u.AvatarUrl),
+		Bio:             u.Bio,
+		Role:            u.Role,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		TOTPEnabled:     u.TOTPEnabled(),
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+func UserProfiles(users []User) []UserProfile {
+	profiles := make([]UserProfile, len(users))
+	for i, user := range users {
+		profiles[i] = user.Profile()
+	}
+
+	return profiles
+}
+
+// MarshalJSON renders a user as its Author view, so embedding a user in a
+// response never leaks more than that. Responses meant for the user itself
+// or user managers send its Profile instead.
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.Author())
+}
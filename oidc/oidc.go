@@ -0,0 +1,64 @@
+// Package oidc signs users in with external identity providers, through the
+// OAuth 2.0 authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Identity is a user as asserted by a provider. Subject is stable and
+// unique per provider, unlike Email.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// AuthRequest is what ties an authorization request to its callback. The
+// caller keeps it out of reach of others between both, e.g. encrypted in a
+// cookie.
+type AuthRequest struct {
+	RedirectURL string
+	State       string
+	Nonce       string
+	// Verifier is the PKCE code verifier, see NewAuthRequest.
+	Verifier string
+}
+
+// Provider is an identity provider users can sign in with.
+type Provider interface {
+	// AuthCodeURL returns where to send the user to sign in, req.RedirectURL
+	// receives the code.
+	AuthCodeURL(ctx context.Context, req AuthRequest) (string, error)
+	// Exchange redeems the code the provider sent to req.RedirectURL and
+	// returns who signed in.
+	Exchange(ctx context.Context, req AuthRequest, code string) (*Identity, error)
+}
+
+// NewAuthRequest returns an AuthRequest with a fresh random state, nonce
+// and PKCE code verifier.
+func NewAuthRequest(redirectURL string) (AuthRequest, error) {
+	req := AuthRequest{RedirectURL: redirectURL}
+
+	for _, value := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return req, err
+		}
+
+		*value = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	return req, nil
+}
+
+// CodeChallenge is the S256 PKCE challenge of the code verifier of req.
+func (req AuthRequest) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(req.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
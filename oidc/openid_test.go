@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// mockProvider is an OpenID Connect provider serving discovery, its keys
+// and a token endpoint answering with the ID token of claims.
+type mockProvider struct {
+	*httptest.Server
+	t *testing.T
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PrivateKey
+	signKid   string
+	claims    jwt.MapClaims
+	jwksHits  int
+	tokenForm url.Values
+	tokenAuth [2]string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	m := &mockProvider{t: t, keys: map[string]*rsa.PrivateKey{}}
+	m.addKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JwksURI:               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.jwksHits++
+
+		set := struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{}
+		for kid, key := range m.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		r.ParseForm()
+		m.tokenForm = r.PostForm
+		m.tokenAuth[0], m.tokenAuth[1], _ = r.BasicAuth()
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims)
+		token.Header["kid"] = m.signKid
+
+		signed, err := token.SignedString(m.keys[m.signKid])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(tokenResponse{IDToken: signed})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// addKey publishes a new key and signs the next ID tokens with it.
+func (m *mockProvider) addKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[kid] = key
+	m.signKid = kid
+}
+
+// validClaims are the claims of a valid ID token for req.
+func (m *mockProvider) validClaims(req AuthRequest) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            "subject-1",
+		"aud":            "client-1",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          req.Nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+	}
+}
+
+func (m *mockProvider) openID() *OpenID {
+	return &OpenID{Issuer: m.URL, ClientID: "client-1", ClientSecret: "secret-1", Client: m.Client()}
+}
+
+func (m *mockProvider) exchange(p *OpenID, req AuthRequest, claims jwt.MapClaims) (*Identity, error) {
+	m.mu.Lock()
+	m.claims = claims
+	m.mu.Unlock()
+
+	return p.Exchange(context.Background(), req, "code-1")
+}
+
+func newTestAuthRequest(t *testing.T) AuthRequest {
+	req, err := NewAuthRequest("https://blog.example.com/auth/oidc/mock/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestOpenIDExchange(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.openID()
+	req := newTestAuthRequest(t)
+
+	identity, err := m.exchange(p, req, m.validClaims(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Identity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	if got := m.tokenForm.Get("code_verifier"); got != req.Verifier {
+		t.Errorf("code_verifier = %q, want %q", got, req.Verifier)
+	}
+	if m.tokenForm.Get("code") != "code-1" || m.tokenForm.Get("redirect_uri") != req.RedirectURL {
+		t.Errorf("token request form = %v", m.tokenForm)
+	}
+	if m.tokenAuth != [2]string{"client-1", "secret-1"} {
+		t.Errorf("client authentication = %v", m.tokenAuth)
+	}
+}
+
+func TestOpenIDAuthCodeURL(t *testing.T) {
+	m := newMockProvider(t)
+	req := newTestAuthRequest(t)
+
+	authURL, err := m.openID().AuthCodeURL(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+
+	if !strings.HasPrefix(authURL, m.URL+"/authorize?") {
+		t.Errorf("url = %v", authURL)
+	}
+	if query.Get("code_challenge") != req.CodeChallenge() || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("PKCE challenge = %v %v", query.Get("code_challenge"), query.Get("code_challenge_method"))
+	}
+	if query.Get("state") != req.State || query.Get("nonce") != req.Nonce {
+		t.Errorf("state or nonce = %v %v", query.Get("state"), query.Get("nonce"))
+	}
+	// the verifier itself never leaves the server
+	if strings.Contains(authURL, req.Verifier) {
+		t.Error("url contains the code verifier")
+	}
+}
+
+func TestOpenIDRejectsInvalidIDTokens(t *testing.T) {
+	m := newMockProvider(t)
+	req := newTestAuthRequest(t)
+
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+	}{
+		{"wrong nonce", func(claims jwt.MapClaims) { claims["nonce"] = "other" }},
+		{"no nonce", func(claims jwt.MapClaims) { delete(claims, "nonce") }},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "client-2" }},
+		{"wrong authorized party", func(claims jwt.MapClaims) {
+			claims["aud"] = []string{"client-1", "client-2"}
+			claims["azp"] = "client-2"
+		}},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"no expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{"no subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := m.validClaims(req)
+			tt.modify(claims)
+
+			if identity, err := m.exchange(m.openID(), req, claims); err == nil {
+				t.Errorf("accepted %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOpenIDRefreshesKeysForUnknownKid(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.openID()
+	req := newTestAuthRequest(t)
+
+	if _, err := m.exchange(p, req, m.validClaims(req)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the provider rotates its key right after the keys were fetched
+	m.addKey("key-2")
+
+	if _, err := m.exchange(p, req, m.validClaims(req)); err == nil {
+		t.Error("keys were fetched again before jwksRefreshInterval")
+	}
+	if m.jwksHits != 1 {
+		t.Errorf("keys fetched %v times, want 1", m.jwksHits)
+	}
+
+	p.mu.Lock()
+	p.keysLoadedAt = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+
+	if _, err := m.exchange(p, req, m.validClaims(req)); err != nil {
+		t.Errorf("token of the rotated key: %v", err)
+	}
+	if m.jwksHits != 2 {
+		t.Errorf("keys fetched %v times, want 2", m.jwksHits)
+	}
+}
@@ -2,8 +2,14 @@ package routes
 
 import (
 	"final-project/controllers"
+	"final-project/mailer"
 	"final-project/middlewares"
+	"final-project/models"
+	"final-project/oidc"
+	"final-project/storage"
+	"final-project/utils"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,33 +18,77 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, store storage.Storage, mail mailer.Mailer, providers map[string]oidc.Provider) *gin.Engine {
 	r := gin.Default()
 
+	// only the proxies of TRUSTED_PROXIES, comma separated IPs or CIDRs, may
+	// tell the client IP through X-Forwarded-For, else anyone could dodge
+	// the login lockout by IP
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		panic(err.Error())
+	}
+
 	r.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		c.Set("storage", store)
+		c.Set("mailer", mail)
+		c.Set("oidc", providers)
 	})
 
 	// auth
 	r.POST("/register", controllers.RegisterUser)
 	r.POST("/login", controllers.LoginUser)
+	r.POST("/auth/refresh", controllers.RefreshToken)
+	r.GET("/auth/verify", controllers.VerifyEmail)
+	r.POST("/auth/forgot-password", controllers.ForgotPassword)
+	r.POST("/auth/reset-password", controllers.ResetPassword)
+	r.GET("/auth/oidc/:provider", controllers.OIDCLogin)
+	r.GET("/auth/oidc/:provider/callback", controllers.OIDCCallback)
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
 	authRoutes := r.Group("/")
 	authRoutes.Use(middlewares.JwtAuth())
-	authRoutes.PATCH("/change-password", controllers.ChangePassword)
 	authRoutes.GET("/my-profile", controllers.MyProfile)
+	// API keys need the profile:write scope to act on the profile
+	canWriteProfile := middlewares.RequirePermission(models.PermProfileWrite)
+	authRoutes.PUT("/my-profile", canWriteProfile, controllers.UpdateMyProfile)
+	authRoutes.POST("/auth/verify/resend", canWriteProfile, controllers.ResendVerificationEmail)
+
+	// account security, not for API keys
+	sessionRoutes := authRoutes.Group("/")
+	sessionRoutes.Use(middlewares.RequireSession())
+	sessionRoutes.PATCH("/change-password", controllers.ChangePassword)
+	sessionRoutes.POST("/logout", controllers.LogoutUser)
+	sessionRoutes.POST("/auth/2fa/enroll", controllers.EnrollTOTP)
+	sessionRoutes.POST("/auth/2fa/confirm", controllers.ConfirmTOTP)
+	sessionRoutes.POST("/auth/2fa/disable", controllers.DisableTOTP)
+	sessionRoutes.POST("/auth/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
+	// api keys
+	apiKeyRoutes := r.Group("/api-keys")
+	apiKeyRoutes.Use(middlewares.JwtAuth(), middlewares.RequireSession())
+	apiKeyRoutes.GET("", controllers.GetAPIKeys)
+	apiKeyRoutes.POST("", controllers.CreateAPIKey)
+	apiKeyRoutes.DELETE("/:id", controllers.RevokeAPIKey)
 
 	// users
 	userRoutes := r.Group("/users")
-	userRoutes.Use(middlewares.JwtAuth(), middlewares.AdminOnly())
+	userRoutes.Use(middlewares.JwtAuth(), middlewares.RequirePermission(models.PermUserManage))
 	userRoutes.GET("/", controllers.GetUsers)
 	userRoutes.GET("/:id", controllers.GetUser)
 	userRoutes.POST("/", controllers.CreateUser)
 	userRoutes.PUT("/:id", controllers.UpdateUser)
 	userRoutes.DELETE("/:id", controllers.DeleteUser)
+	userRoutes.POST("/:id/unlock", controllers.UnlockUser)
+	userRoutes.DELETE("/:id/2fa", controllers.ResetUserTOTP)
+
+	// audit logs
+	auditLogRoutes := r.Group("/audit-logs")
+	auditLogRoutes.Use(middlewares.JwtAuth(), middlewares.RequirePermission(models.PermUserManage))
+	auditLogRoutes.GET("", controllers.GetAuditLogs)
 
 	// categories
 	categoriesRoutes := r.Group("/categories")
-	categoriesRoutes.Use(middlewares.JwtAuth(), middlewares.AdminOnly())
+	categoriesRoutes.Use(middlewares.JwtAuth(), middlewares.RequirePermission(models.PermCategoryManage))
 	r.GET("/categories", controllers.GetCategories)
 	r.GET("/categories/:id", controllers.GetCategory)
 	categoriesRoutes.POST("/", controllers.CreateCategory)
@@ -47,7 +97,7 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 
 	// tags
 	tagsRoutes := r.Group("/tags")
-	tagsRoutes.Use(middlewares.JwtAuth(), middlewares.AdminOnly())
+	tagsRoutes.Use(middlewares.JwtAuth(), middlewares.RequirePermission(models.PermTagManage))
 	r.GET("/tags", controllers.GetTags)
 	r.GET("/tags/:id", controllers.GetTag)
 	tagsRoutes.POST("/", controllers.CreateTag)
@@ -56,27 +106,45 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 
 	// articles
 	articleRoutes := r.Group("/articles")
-	articleRoutes.Use(middlewares.JwtAuth(), middlewares.AdminOnly())
-	r.GET("/articles", controllers.GetArticles)
-	r.GET("/articles/:id", controllers.GetArticle)
-	r.GET("/articles/slug/:slug", controllers.GetArticleBySlug)
-	r.GET("/articles/tag/:tag", controllers.GetArticleByTag)
-	r.GET("/articles/category/:id", controllers.GetArticleByCategory)
-	articleRoutes.POST("/", controllers.CreateArticle)
-	articleRoutes.PUT("/:id", controllers.UpdateArticle)
-	articleRoutes.DELETE("/:id", controllers.DeleteArticle)
-	articleRoutes.PATCH("/publish/:id", controllers.PublishArticle)
-	articleRoutes.PATCH("/unpublish/:id", controllers.UnpublishArticle)
+	articleRoutes.Use(middlewares.JwtAuth())
+	publicArticleRoutes := r.Group("/articles")
+	publicArticleRoutes.Use(middlewares.OptionalJwtAuth())
+	publicArticleRoutes.GET("", controllers.GetArticles)
+	publicArticleRoutes.GET("/search", controllers.SearchArticles)
+	publicArticleRoutes.GET("/:id", controllers.GetArticle)
+	publicArticleRoutes.GET("/slug/:slug", controllers.GetArticleBySlug)
+	publicArticleRoutes.GET("/tag/:tag", controllers.GetArticleByTag)
+	publicArticleRoutes.GET("/category/:id", controllers.GetArticleByCategory)
+	// the controllers check that writers only touch their own articles
+	canWrite := middlewares.RequirePermission(models.PermArticleWrite)
+	canPublish := middlewares.RequirePermission(models.PermArticlePublish)
+	articleRoutes.POST("/", canWrite, controllers.CreateArticle)
+	articleRoutes.PUT("/:id", canWrite, controllers.UpdateArticle)
+	articleRoutes.DELETE("/:id", canWrite, controllers.DeleteArticle)
+	articleRoutes.PATCH("/publish/:id", canPublish, controllers.PublishArticle)
+	articleRoutes.PATCH("/unpublish/:id", canPublish, controllers.UnpublishArticle)
+	articleRoutes.GET("/scheduled", canPublish, controllers.GetScheduledArticles)
+	articleRoutes.PATCH("/schedule/:id", canPublish, controllers.ScheduleArticle)
+	articleRoutes.DELETE("/schedule/:id", canPublish, controllers.CancelScheduledArticle)
+	articleRoutes.GET("/:id/revisions", canWrite, controllers.GetArticleRevisions)
+	articleRoutes.GET("/:id/revisions/diff", canWrite, controllers.DiffArticleRevisions)
+	articleRoutes.POST("/:id/revisions/:rev/restore", canWrite, controllers.RestoreArticleRevision)
 
+	// API keys need the comment:write scope to post or delete comments
 	commentRoutes := r.Group("/articles")
-	commentRoutes.Use(middlewares.JwtAuth())
-	r.GET("/articles/:id/comments", controllers.GetComments)
+	commentRoutes.Use(middlewares.JwtAuth(), middlewares.RequirePermission(models.PermCommentWrite))
+	publicArticleRoutes.GET("/:id/comments", controllers.GetComments)
 	commentRoutes.POST("/:id/comments", controllers.CreateComment)
 	commentRoutes.DELETE("/comments/:id", controllers.DeleteComment)
-	r.GET("/articles/comments/:id/replies", controllers.GetReplyComments)
+	publicArticleRoutes.GET("/comments/:id/replies", controllers.GetReplyComments)
 	commentRoutes.POST("/comments/:id/replies", controllers.CreateReplyComment)
 	commentRoutes.DELETE("/comments/replies/:id", controllers.DeleteReplyComment)
 
+	// uploads
+	uploadRoutes := r.Group("/uploads")
+	uploadRoutes.Use(middlewares.JwtAuth())
+	uploadRoutes.POST("", middlewares.RequirePermission(models.PermArticleWrite), controllers.UploadImage)
+
 	// docs
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -84,3 +152,15 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	r.StaticFS("/file", http.Dir("public"))
 	return r
 }
+
+func trustedProxies() []string {
+	var proxies []string
+
+	for _, proxy := range strings.Split(utils.GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
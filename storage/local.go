@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local stores files on the local disk under Dir, served by the API itself
+// at BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	name := l.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) URL(key string) (string, error) {
+	return l.BaseURL + "/" + cleanKey(key), nil
+}
+
+// ReadURL returns url, files on the local disk are always public.
+func (l *Local) ReadURL(url string) (string, error) {
+	return url, nil
+}
+
+func (l *Local) StoredURL(url string) string {
+	return url
+}
+
+// SignedURL returns the plain URL, files on the local disk are always
+// public.
+func (l *Local) SignedURL(key string, _ time.Duration) (string, error) {
+	return l.URL(key)
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(cleanKey(key)))
+}
+
+// cleanKey keeps keys inside the storage root.
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
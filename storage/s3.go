@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3MaxExpiry       = 7 * 24 * time.Hour
+)
+
+// S3 stores files in a bucket of any S3 compatible service, such as AWS S3
+// or MinIO. Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	// Endpoint is the service base URL, e.g. https://s3.amazonaws.com or
+	// http://localhost:9000 for MinIO.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as Endpoint/Bucket instead of as a
+	// subdomain, MinIO needs it.
+	PathStyle bool
+	// PublicURL optionally replaces the bucket URL for public objects, e.g.
+	// a CDN in front of the bucket.
+	PublicURL string
+	// Private objects are only reachable through signed URLs valid for
+	// URLExpiry, see ReadURL.
+	Private   bool
+	URLExpiry time.Duration
+	Client    *http.Client
+
+	now func() time.Time
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, _ int64, contentType string) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, body)
+
+	return s.do(req)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+
+	s.sign(req, nil)
+
+	return s.do(req)
+}
+
+func (s *S3) URL(key string) (string, error) {
+	if s.PublicURL != "" && !s.Private {
+		return strings.TrimSuffix(s.PublicURL, "/") + "/" + encodePath(cleanKey(key)), nil
+	}
+
+	return s.objectURL(key), nil
+}
+
+// ReadURL signs url for URLExpiry when the bucket is private and url is the
+// address of one of its objects.
+func (s *S3) ReadURL(url string) (string, error) {
+	key, ok := s.key(url)
+	if !s.Private || !ok {
+		return url, nil
+	}
+
+	return s.SignedURL(key, s.URLExpiry)
+}
+
+func (s *S3) StoredURL(url string) string {
+	if key, ok := s.key(url); s.Private && ok {
+		return s.objectURL(key)
+	}
+
+	return url
+}
+
+// key returns the key of the object addressed by rawURL, signed or not.
+func (s *S3) key(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	prefix := s.objectURL("")
+	address := u.Scheme + "://" + u.Host + u.EscapedPath()
+
+	if !strings.HasPrefix(address, prefix) || address == prefix {
+		return "", false
+	}
+
+	key, err := url.PathUnescape(strings.TrimPrefix(address, prefix))
+	if err != nil {
+		return "", false
+	}
+
+	return key, true
+}
+
+// SignedURL presigns a GET request for key. S3 caps expiry at 7 days.
+func (s *S3) SignedURL(key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > s3MaxExpiry {
+		return "", fmt.Errorf("s3: signed url expiry must be between 1s and %v", s3MaxExpiry)
+	}
+
+	u, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+
+	now := s.clock().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	signature := s.signature(now, amzDate, canonicalRequest)
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+
+	return u.String(), nil
+}
+
+func (s *S3) objectURL(key string) string {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		endpoint = &url.URL{Scheme: "https", Host: s.Endpoint}
+	}
+
+	key = encodePath(cleanKey(key))
+
+	if s.PathStyle {
+		return fmt.Sprintf("%v://%v/%v/%v", endpoint.Scheme, endpoint.Host, s.Bucket, key)
+	}
+
+	return fmt.Sprintf("%v://%v.%v/%v", endpoint.Scheme, s.Bucket, endpoint.Host, key)
+}
+
+// sign adds the Authorization header to req, covering its host, content type
+// and body.
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.clock().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQueryString(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		s3Algorithm,
+		s.AccessKey,
+		s.scope(now),
+		signedHeaders,
+		s.signature(now, amzDate, canonicalRequest),
+	))
+}
+
+func (s *S3) signature(now time.Time, amzDate, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzDate,
+		s.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+func (s *S3) do(req *http.Request) error {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("s3: %v %v: %v %v", req.Method, req.URL.Path, res.Status, string(msg))
+	}
+
+	return nil
+}
+
+func (s *S3) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func encodePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// uriEncode percent-encodes everything but the RFC 3986 unreserved
+// characters, as SigV4 requires. Slashes are kept unless encodeSlash.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (ch == '/' && !encodeSlash) {
+			b.WriteByte(ch)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
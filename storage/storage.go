@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Storage keeps uploaded files. Keys are slash separated paths relative to
+// the storage root, such as "images/1659-ab12.png".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL returns the address of key, which is stored with the file. It
+	// doesn't expire, storages keeping private objects sign it when it is
+	// sent, see ReadURL. Local storages may return a path relative to the
+	// API host, see utils.PublicURL.
+	URL(key string) (string, error)
+	// ReadURL returns url, an address returned by URL, as clients download
+	// it from: signed for storages keeping private objects. Other URLs are
+	// returned unchanged.
+	ReadURL(url string) (string, error)
+	// StoredURL undoes ReadURL, it returns the address of the file a
+	// client sent the read URL of. Other URLs are returned unchanged.
+	StoredURL(url string) string
+	// SignedURL returns a URL granting read access to key until expiry.
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
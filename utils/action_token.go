@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Purposes of action tokens, a token is only accepted for its own purpose.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// ActionClaims are the claims of an action token: who it acts for and the
+// stamp of the state it was issued for, see GenerateActionToken.
+type ActionClaims struct {
+	UserID uint
+	Stamp  string
+}
+
+// actionTokenKey signs action tokens. It is derived from API_SECRET rather
+// than being API_SECRET, so action and access tokens can't pass for each
+// other.
+func actionTokenKey() []byte {
+	mac := hmac.New(sha256.New, []byte(APISecret()))
+	mac.Write([]byte("action token"))
+	return mac.Sum(nil)
+}
+
+// GenerateActionToken signs a token letting its holder perform purpose for
+// userID until ttl has passed. stamp should change once the action is done,
+// e.g. a hash of the password for a password reset, so the caller checking
+// it makes the token single use.
+func GenerateActionToken(purpose string, userID uint, stamp string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["purpose"] = purpose
+	claims["user_id"] = userID
+	claims["stamp"] = stamp
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(actionTokenKey())
+}
+
+// ParseActionToken checks the signature, expiry and purpose of an action
+// token. The caller still has to compare the stamp.
+func ParseActionToken(tokenString string, purpose string) (*ActionClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return actionTokenKey(), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := claimToUint(claims, "user_id")
+	if err != nil {
+		return nil, err
+	}
+
+	stamp, _ := claims["stamp"].(string)
+
+	return &ActionClaims{UserID: userID, Stamp: stamp}, nil
+}
+
+func VerifyTokenLifespan() (time.Duration, error) {
+	tokenLife, err := strconv.Atoi(GetEnv("VERIFY_TOKEN_HOUR_LIFESPAN", "48"))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Hour * time.Duration(tokenLife), nil
+}
+
+func ResetTokenLifespan() (time.Duration, error) {
+	tokenLife, err := strconv.Atoi(GetEnv("RESET_TOKEN_MINUTE_LIFESPAN", "30"))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Minute * time.Duration(tokenLife), nil
+}
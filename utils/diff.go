@@ -0,0 +1,106 @@
+package utils
+
+import "strings"
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the LCS table of DiffLines, which takes one int per
+// pair of changed lines.
+const maxDiffCells = 1 << 20
+
+// DiffLines returns a line based diff turning a into b. Op is "=" for
+// unchanged lines, "-" for lines only in a and "+" for lines only in b.
+// Lines shared at the start and end are matched first, when the lines left
+// in between are too many to compare pairwise they are all removed then
+// added.
+func DiffLines(a, b string) []DiffLine {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	diff := []DiffLine{}
+	for _, line := range x[:prefix] {
+		diff = append(diff, DiffLine{Op: "=", Text: line})
+	}
+
+	diff = append(diff, diffMiddle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+
+	for _, line := range x[len(x)-suffix:] {
+		diff = append(diff, DiffLine{Op: "=", Text: line})
+	}
+
+	return diff
+}
+
+// diffMiddle diffs x and y through their longest common subsequence, or
+// replaces x with y as a whole when the table would exceed maxDiffCells.
+func diffMiddle(x, y []string) []DiffLine {
+	diff := []DiffLine{}
+
+	if (len(x)+1)*(len(y)+1) > maxDiffCells {
+		for _, line := range x {
+			diff = append(diff, DiffLine{Op: "-", Text: line})
+		}
+		for _, line := range y {
+			diff = append(diff, DiffLine{Op: "+", Text: line})
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, DiffLine{Op: "=", Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: "-", Text: x[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: "+", Text: y[j]})
+			j++
+		}
+	}
+
+	for ; i < len(x); i++ {
+		diff = append(diff, DiffLine{Op: "-", Text: x[i]})
+	}
+
+	for ; j < len(y); j++ {
+		diff = append(diff, DiffLine{Op: "+", Text: y[j]})
+	}
+
+	return diff
+}
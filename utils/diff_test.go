@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sides rebuilds the two texts a diff was made from.
+func sides(diff []DiffLine) (string, string) {
+	a, b := []string{}, []string{}
+	for _, line := range diff {
+		if line.Op != "+" {
+			a = append(a, line.Text)
+		}
+		if line.Op != "-" {
+			b = append(b, line.Text)
+		}
+	}
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := DiffLines("a\nb\nc\nd", "a\nc\nx\nd")
+
+	want := []DiffLine{{"=", "a"}, {"-", "b"}, {"=", "c"}, {"+", "x"}, {"=", "d"}}
+	if fmt.Sprint(diff) != fmt.Sprint(want) {
+		t.Errorf("diff = %v, want %v", diff, want)
+	}
+
+	for _, texts := range [][2]string{{"", ""}, {"", "a"}, {"a", ""}, {"a\na", "a"}, {"a\nb", "b\na"}} {
+		if a, b := sides(DiffLines(texts[0], texts[1])); a != texts[0] || b != texts[1] {
+			t.Errorf("diff of %q and %q rebuilds %q and %q", texts[0], texts[1], a, b)
+		}
+	}
+}
+
+func TestDiffLinesLargeChange(t *testing.T) {
+	x, y := []string{"head"}, []string{"head"}
+	for i := 0; i < 5000; i++ {
+		x = append(x, fmt.Sprint("old ", i))
+		y = append(y, fmt.Sprint("new ", i))
+	}
+	x, y = append(x, "tail"), append(y, "tail")
+	a, b := strings.Join(x, "\n"), strings.Join(y, "\n")
+
+	diff := DiffLines(a, b)
+
+	if got, _ := sides(diff); got != a {
+		t.Error("diff doesn't rebuild the old text")
+	}
+	if _, got := sides(diff); got != b {
+		t.Error("diff doesn't rebuild the new text")
+	}
+
+	// the shared first and last lines are kept, the rest replaced as a whole
+	if len(diff) != 10002 || diff[0].Op != "=" || diff[1].Op != "-" || diff[5001].Op != "+" || diff[10001].Op != "=" {
+		t.Errorf("unexpected diff of %v lines", len(diff))
+	}
+}
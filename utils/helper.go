@@ -1,54 +1,151 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"final-project/apperrors"
+	"final-project/storage"
 	"fmt"
 	"io"
-	"log"
-	"os"
+	"mime/multipart"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SliceStringToUInt(data []string) []uint {
+// SliceStringToUInt parses ids as the "ids" validator checks them: spaces
+// around an id and empty ids are ignored.
+func SliceStringToUInt(data []string) ([]uint, error) {
 	ids := []uint{}
 
 	for _, d := range data {
-		id, err := strconv.Atoi(d)
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
 
+		id, err := strconv.ParseUint(d, 10, 32)
 		if err != nil {
-			fmt.Println(err.Error())
+			return nil, err
 		}
 
 		ids = append(ids, uint(id))
 	}
-	return ids
+	return ids, nil
 }
 
-func UploadFile(c *gin.Context, folder string, name string) (string, error) {
-	file, header, err := c.Request.FormFile(name)
+var (
+	ErrFileTooLarge        = apperrors.New(apperrors.KindTooLarge, "FILE_TOO_LARGE")
+	ErrUnsupportedFileType = apperrors.New(apperrors.KindUnsupportedMediaType, "FILE_TYPE_UNSUPPORTED")
+)
+
+var ImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
 
+// UploadFile stores the multipart file sent in field name in store under
+// <folder>/ and returns its storage key, see storage.Storage.URL. The type
+// is sniffed from the content and must be a key of allowed, which also
+// gives the file extension.
+func UploadFile(c *gin.Context, store storage.Storage, folder string, name string, allowed map[string]string) (string, error) {
+	file, header, contentType, err := openUpload(c, name, allowed)
 	if err != nil {
 		return "", err
 	}
+	defer file.Close()
 
-	timeStamp := strconv.Itoa(int(time.Now().Unix()))
-	fileName := timeStamp + "-" + header.Filename
-	path := fmt.Sprintf("public/upload/%v/%v", folder, fileName)
+	key, err := newUploadKey(folder, allowed[contentType])
+	if err != nil {
+		return "", err
+	}
 
-	out, err := os.Create(path)
-	defer out.Close()
+	if err := store.Put(c.Request.Context(), key, file, header.Size, contentType); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
 
+// openUpload opens the multipart file sent in field name after checking its
+// size against UPLOAD_MAX_SIZE_MB and its sniffed type against allowed.
+func openUpload(c *gin.Context, name string, allowed map[string]string) (multipart.File, *multipart.FileHeader, string, error) {
+	maxSize, err := strconv.Atoi(GetEnv("UPLOAD_MAX_SIZE_MB", "2"))
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, "", err
 	}
+	maxBytes := int64(maxSize) << 20
 
-	_, err = io.Copy(out, file)
+	// leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	file, header, err := c.Request.FormFile(name)
 	if err != nil {
-		log.Fatal(err)
+		// http.MaxBytesError needs go1.19, see the Procfile go version
+		if strings.Contains(err.Error(), "request body too large") {
+			return nil, nil, "", ErrFileTooLarge
+		}
+		return nil, nil, "", err
+	}
+
+	if header.Size > maxBytes {
+		file.Close()
+		return nil, nil, "", ErrFileTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		file.Close()
+		return nil, nil, "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if _, ok := allowed[contentType]; !ok {
+		file.Close()
+		return nil, nil, "", ErrUnsupportedFileType
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, "", err
+	}
+
+	return file, header, contentType, nil
+}
+
+// newUploadKey returns a unique key for a new file in folder.
+func newUploadKey(folder string, ext string) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	timeStamp := strconv.Itoa(int(time.Now().Unix()))
+	return fmt.Sprintf("%v/%v-%v%v", folder, timeStamp, hex.EncodeToString(random), ext), nil
+}
+
+// PublicURL turns a path served by this API into an absolute URL, based on
+// BASE_URL or, when unset, the host the request was sent to. Absolute URLs,
+// such as those of an S3 storage, are returned as is.
+func PublicURL(c *gin.Context, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+
+	base := GetEnv("BASE_URL", "")
+
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
 	}
-	filepath := fmt.Sprintf("file/upload/%v/%v", folder, fileName)
 
-	return filepath, nil
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
 }
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slices"
+)
+
+func TestSliceStringToUIntMatchesValidator(t *testing.T) {
+	v := binding.Validator.Engine().(*validator.Validate)
+
+	tests := []struct {
+		input string
+		ids   []uint
+		valid bool
+	}{
+		{"", []uint{}, true},
+		{"1,2", []uint{1, 2}, true},
+		{"1, 2", []uint{1, 2}, true},
+		{" 1 ,, 2 ,", []uint{1, 2}, true},
+		{"1,x", nil, false},
+		{"1,-2", nil, false},
+		{"4294967296", nil, false},
+	}
+
+	for _, tt := range tests {
+		valid := v.Var(tt.input, "ids") == nil
+		if valid != tt.valid {
+			t.Errorf("validator accepts %q: %v, want %v", tt.input, valid, tt.valid)
+		}
+
+		ids, err := SliceStringToUInt(strings.Split(tt.input, ","))
+		if (err == nil) != tt.valid {
+			t.Errorf("SliceStringToUInt(%q) error = %v", tt.input, err)
+		}
+		if tt.valid && !slices.Equal(ids, tt.ids) {
+			t.Errorf("SliceStringToUInt(%q) = %v, want %v", tt.input, ids, tt.ids)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is used when the request has no Accept-Language header or
+// asks only for languages we have no translation for.
+const DefaultLanguage = "id"
+
+// the first language is the default one
+var supportedLanguages = []language.Tag{language.Indonesian, language.English}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+// messages is the message catalog, by language then key. Parameters are
+// written {name}. Every key must exist in the default language.
+var messages = map[string]map[string]string{
+	"id": {
+		"not_found":                 "data tidak ditemukan",
+		"internal_error":            "terjadi kesalahan pada server",
+		"validation_failed":         "input tidak valid",
+		"permission_denied":         "anda tidak memiliki izin {permission}",
+		"invalid_token":             "token tidak valid",
+		"token_expired":             "token sudah kedaluwarsa",
+		"token_revoked":             "token sudah dicabut",
+		"invalid_refresh_token":     "refresh token tidak valid",
+		"invalid_credentials":       "email atau password salah",
+		"login_locked":              "terlalu banyak percobaan login yang gagal, coba lagi dalam {seconds} detik",
+		"totp_required":             "masukkan kode autentikasi dua faktor",
+		"invalid_otp":               "kode autentikasi dua faktor salah",
+		"totp_already_enabled":      "autentikasi dua faktor sudah aktif",
+		"totp_not_enrolled":         "autentikasi dua faktor belum didaftarkan",
+		"totp_mandatory":            "role anda wajib menggunakan autentikasi dua faktor",
+		"totp_enrollment_required":  "aktifkan autentikasi dua faktor untuk menggunakan izin role anda",
+		"totp_disabled":             "autentikasi dua faktor dinonaktifkan",
+		"oidc_state_invalid":        "permintaan login tidak valid atau kedaluwarsa, silakan ulangi",
+		"oidc_login_failed":         "login dengan penyedia identitas gagal",
+		"oidc_email_required":       "penyedia identitas tidak memberikan email",
+		"oidc_email_taken":          "email sudah terdaftar dan belum diverifikasi oleh penyedia identitas atau pemiliknya",
+		"oidc_totp_enabled":         "akun dengan autentikasi dua faktor harus login dengan password",
+		"oidc_link_privileged":      "akun dengan hak akses ini harus login dengan password",
+		"invalid_api_key":           "api key tidak valid, kedaluwarsa atau sudah dicabut",
+		"api_key_not_allowed":       "aksi ini tidak dapat dilakukan dengan api key",
+		"old_password_mismatch":     "password lama tidak cocok",
+		"password_changed":          "berhasil ganti password",
+		"search_query_required":     "query pencarian harus diisi",
+		"search_cursor_unsupported": "pencarian tidak mendukung pagination cursor",
+		"article_already_published": "artikel sudah dipublish",
+		"comment_author_only":       "hanya pembuat komentar atau moderator yang dapat menghapus komentar",
+		"article_author_only":       "hanya penulis artikel atau editor yang dapat mengubah artikel ini",
+		"revision_not_found":        "revisi '{revision}' tidak ditemukan",
+		"invalid_param":             "{param} tidak valid",
+		"sort_unsupported":          "sort '{sort}' tidak didukung",
+		"cursor_sort_unsupported":   "pagination cursor hanya mendukung sort 'id' atau '-id'",
+		"file_required":             "file harus diisi",
+		"file_too_large":            "ukuran file terlalu besar",
+		"file_type_unsupported":     "tipe file tidak didukung",
+		"image_too_large":           "dimensi gambar terlalu besar",
+		"image_invalid":             "file gambar tidak valid",
+		"email_verified":            "email berhasil diverifikasi",
+		"email_already_verified":    "email sudah diverifikasi",
+		"verification_sent":         "email verifikasi sudah dikirim",
+		"password_reset_sent":       "jika email terdaftar, link reset password sudah dikirim",
+		"password_reset":            "password berhasil direset, silakan login kembali",
+		"invalid_action_token":      "token tidak valid atau sudah kedaluwarsa",
+		"mail.verify_subject":       "Verifikasi email anda",
+		"mail.verify_body":          "Halo {name},\n\nbuka link berikut untuk memverifikasi email anda:\n\n{link}\n\nLink ini berlaku selama {hours} jam.",
+		"mail.reset_subject":        "Reset password",
+		"mail.reset_body":           "Halo {name},\n\nkami menerima permintaan reset password untuk akun anda. Gunakan link berikut untuk membuat password baru:\n\n{link}\n\nLink ini berlaku selama {minutes} menit. Abaikan email ini jika anda tidak memintanya.",
+		"validation.invalid":        "{field} tidak valid",
+		"validation.required":       "{field} harus diisi",
+		"validation.email":          "{field} harus berupa email yang valid",
+		"validation.min":            "{field} minimal {param} karakter",
+		"validation.max":            "{field} maksimal {param} karakter",
+		"validation.oneof":          "{field} harus salah satu dari: {param}",
+		"validation.httpurl":        "{field} harus berupa url http atau https",
+		"validation.slug":           "{field} hanya boleh berisi huruf kecil, angka dan tanda hubung",
+		"validation.future":         "{field} harus di masa depan",
+		"validation.ids":            "{field} harus berupa daftar id yang dipisahkan koma",
+		"validation.unique":         "{field} sudah digunakan",
+		"validation.exists":         "{field} berisi id yang tidak ditemukan: {param}",
+		"validation.type":           "{field} memiliki tipe data yang salah",
+		"validation.time":           "waktu harus berformat {param}",
+		"validation.json":           "body harus berupa JSON yang valid",
+	},
+	"en": {
+		"not_found":                 "data not found",
+		"internal_error":            "internal server error",
+		"validation_failed":         "invalid input",
+		"permission_denied":         "you lack the {permission} permission",
+		"invalid_token":             "invalid token",
+		"token_expired":             "token has expired",
+		"token_revoked":             "token has been revoked",
+		"invalid_refresh_token":     "invalid refresh token",
+		"invalid_credentials":       "wrong email or password",
+		"login_locked":              "too many failed logins, try again in {seconds} seconds",
+		"totp_required":             "enter your two factor authentication code",
+		"invalid_otp":               "wrong two factor authentication code",
+		"totp_already_enabled":      "two factor authentication is already enabled",
+		"totp_not_enrolled":         "two factor authentication is not enrolled",
+		"totp_mandatory":            "your role must use two factor authentication",
+		"totp_enrollment_required":  "enable two factor authentication to use the permissions of your role",
+		"totp_disabled":             "two factor authentication is disabled",
+		"oidc_state_invalid":        "invalid or expired login request, please try again",
+		"oidc_login_failed":         "login with the identity provider failed",
+		"oidc_email_required":       "the identity provider did not share an email",
+		"oidc_email_taken":          "the email is registered and not verified by the identity provider or its owner",
+		"oidc_totp_enabled":         "accounts with two factor authentication must login with their password",
+		"oidc_link_privileged":      "accounts with these permissions must login with their password",
+		"invalid_api_key":           "the api key is invalid, expired or revoked",
+		"api_key_not_allowed":       "this action can't be taken with an api key",
+		"old_password_mismatch":     "old password does not match",
+		"password_changed":          "password changed",
+		"search_query_required":     "search query is required",
+		"search_cursor_unsupported": "search does not support cursor pagination",
+		"article_already_published": "article is already published",
+		"comment_author_only":       "only the author of a comment or a moderator can delete it",
+		"article_author_only":       "only the author of the article or an editor can change it",
+		"revision_not_found":        "revision '{revision}' not found",
+		"invalid_param":             "invalid {param}",
+		"sort_unsupported":          "sort '{sort}' is not supported",
+		"cursor_sort_unsupported":   "cursor pagination only supports sort 'id' or '-id'",
+		"file_required":             "file is required",
+		"file_too_large":            "file is too large",
+		"file_type_unsupported":     "file type is not supported",
+		"image_too_large":           "image dimensions are too large",
+		"image_invalid":             "invalid image file",
+		"email_verified":            "email verified",
+		"email_already_verified":    "email is already verified",
+		"verification_sent":         "verification email sent",
+		"password_reset_sent":       "if the email is registered, a password reset link has been sent",
+		"password_reset":            "password reset, please log in again",
+		"invalid_action_token":      "invalid or expired token",
+		"mail.verify_subject":       "Verify your email",
+		"mail.verify_body":          "Hi {name},\n\nopen the following link to verify your email:\n\n{link}\n\nThe link is valid for {hours} hours.",
+		"mail.reset_subject":        "Reset your password",
+		"mail.reset_body":           "Hi {name},\n\nwe received a request to reset the password of your account. Use the following link to choose a new password:\n\n{link}\n\nThe link is valid for {minutes} minutes. Ignore this email if you did not ask for it.",
+		"validation.invalid":        "{field} is invalid",
+		"validation.required":       "{field} is required",
+		"validation.email":          "{field} must be a valid email",
+		"validation.min":            "{field} must be at least {param} characters",
+		"validation.max":            "{field} must be at most {param} characters",
+		"validation.oneof":          "{field} must be one of: {param}",
+		"validation.httpurl":        "{field} must be an http or https url",
+		"validation.slug":           "{field} may only contain lowercase letters, digits and hyphens",
+		"validation.future":         "{field} must be in the future",
+		"validation.ids":            "{field} must be a comma separated list of ids",
+		"validation.unique":         "{field} is already taken",
+		"validation.exists":         "{field} contains ids that do not exist: {param}",
+		"validation.type":           "{field} has the wrong type",
+		"validation.time":           "time must be formatted as {param}",
+		"validation.json":           "body must be valid JSON",
+	},
+}
+
+// Message is a catalog message, translated when it is sent to the client.
+type Message struct {
+	Key    string
+	Params map[string]string
+}
+
+// NewMessage returns the catalog message key, params are name, value pairs
+// filling its {name} parameters.
+func NewMessage(key string, params ...string) *Message {
+	m := &Message{Key: key, Params: map[string]string{}}
+
+	for i := 0; i+1 < len(params); i += 2 {
+		m.Params[params[i]] = params[i+1]
+	}
+
+	return m
+}
+
+// Translate returns the message in lang, falling back to DefaultLanguage
+// and then to the key itself.
+func (m *Message) Translate(lang string) string {
+	msg, ok := messages[lang][m.Key]
+	if !ok {
+		msg, ok = messages[DefaultLanguage][m.Key]
+	}
+	if !ok {
+		return m.Key
+	}
+
+	replacements := []string{}
+	for name, value := range m.Params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(msg)
+}
+
+// Language returns the supported language best matching the Accept-Language
+// header of the request.
+func Language(c *gin.Context) string {
+	tags, _, _ := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	_, i, _ := languageMatcher.Match(tags...)
+
+	base, _ := supportedLanguages[i].Base()
+	return base.String()
+}
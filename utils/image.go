@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"final-project/apperrors"
+	"final-project/storage"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+
+	// registers the WebP decoder with image.Decode
+	_ "golang.org/x/image/webp"
+)
+
+// maxImagePixels guards against decompression bombs: small files declaring
+// huge dimensions.
+const maxImagePixels = 40_000_000
+
+var (
+	ErrImageTooLarge = apperrors.New(apperrors.KindTooLarge, "IMAGE_TOO_LARGE")
+	ErrInvalidImage  = apperrors.BadRequest("IMAGE_INVALID")
+)
+
+// ImageSize is a variant generated for every uploaded image, cropped to fill
+// Width x Height.
+type ImageSize struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+var ImageSizes = []ImageSize{
+	{Name: "thumbnail", Width: 300, Height: 300},
+	{Name: "card", Width: 800, Height: 450},
+	{Name: "og", Width: 1200, Height: 630},
+}
+
+// StoredImage is an image file written to the storage.
+type StoredImage struct {
+	Key    string
+	Width  int
+	Height int
+}
+
+// StoredVariant is an ImageSize variant, stored as JPEG (PNG for images with
+// transparency) under Key and as lossless WebP under WebPKey, left empty
+// when the WebP file would be the larger one.
+type StoredVariant struct {
+	StoredImage
+	WebPKey string
+}
+
+type ImageUpload struct {
+	StoredImage
+	ContentType string
+	Size        int64
+	Variants    map[string]StoredVariant
+}
+
+// UploadImage stores the image sent in field name in store under <folder>/,
+// along with its ImageSizes variants. JPEG and PNG images are re-encoded
+// with their EXIF orientation applied, which drops their metadata. GIF
+// images are re-encoded frame by frame, which drops their comments and
+// application extensions. WebP images keep their encoding but lose their
+// EXIF and XMP chunks.
+func UploadImage(c *gin.Context, store storage.Storage, folder string, name string) (*ImageUpload, error) {
+	file, _, contentType, err := openUpload(c, name, ImageTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	switch contentType {
+	case "image/jpeg":
+		data, err = encodeImage(img, imaging.JPEG)
+	case "image/png":
+		data, err = encodeImage(img, imaging.PNG)
+	case "image/gif":
+		data, err = reencodeGIF(data)
+	case "image/webp":
+		data, err = stripWebPMetadata(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newUploadKey(folder, ImageTypes[contentType])
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &ImageUpload{
+		StoredImage: StoredImage{Key: key, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()},
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Variants:    map[string]StoredVariant{},
+	}
+
+	ctx := c.Request.Context()
+	stored := []string{}
+
+	put := func(key string, data []byte, contentType string) error {
+		if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			return err
+		}
+		stored = append(stored, key)
+		return nil
+	}
+
+	err = func() error {
+		if err := put(key, data, contentType); err != nil {
+			return err
+		}
+
+		format, ext, variantType := imaging.JPEG, ".jpg", "image/jpeg"
+		if opaque, ok := img.(interface{ Opaque() bool }); !ok || !opaque.Opaque() {
+			format, ext, variantType = imaging.PNG, ".png", "image/png"
+		}
+
+		base := strings.TrimSuffix(key, ImageTypes[contentType])
+
+		for _, size := range ImageSizes {
+			resized := imaging.Fill(img, size.Width, size.Height, imaging.Center, imaging.Lanczos)
+			variant := StoredVariant{
+				StoredImage: StoredImage{Key: fmt.Sprintf("%v-%v%v", base, size.Name, ext), Width: size.Width, Height: size.Height},
+				WebPKey:     fmt.Sprintf("%v-%v.webp", base, size.Name),
+			}
+
+			encoded, err := encodeImage(resized, format)
+			if err != nil {
+				return err
+			}
+			if err := put(variant.Key, encoded, variantType); err != nil {
+				return err
+			}
+
+			var webp bytes.Buffer
+			if err := EncodeWebP(&webp, resized); err != nil {
+				return err
+			}
+
+			// lossless WebP loses to JPEG on photos, clients preferring
+			// WebP should not download more bytes for it
+			if webp.Len() < len(encoded) {
+				if err := put(variant.WebPKey, webp.Bytes(), "image/webp"); err != nil {
+					return err
+				}
+			} else {
+				variant.WebPKey = ""
+			}
+
+			upload.Variants[size.Name] = variant
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		// best effort, the error that matters is the one above
+		for _, key := range stored {
+			store.Delete(ctx, key)
+		}
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func encodeImage(img image.Image, format imaging.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(85)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// reencodeGIF decodes and encodes every frame of a GIF file, keeping the
+// animation but not the comment and application extensions, such as XMP,
+// the encoder doesn't write.
+func reencodeGIF(data []byte) ([]byte, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	pixels := 0
+	for _, frame := range g.Image {
+		pixels += frame.Bounds().Dx() * frame.Bounds().Dy()
+	}
+	if pixels > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks of a WebP file.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+
+	out := append([]byte{}, data[:12]...)
+
+	for p := 12; p < len(data); {
+		if p+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+
+		fourCC := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4 : p+8]))
+		end := p + 8 + size + size&1
+
+		if size < 0 || p+8+size > len(data) {
+			return nil, ErrInvalidImage
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[p:end]...)
+			if size > 0 {
+				// clear the EXIF and XMP flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[p:end]...)
+		}
+
+		p = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
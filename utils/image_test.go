@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestReencodeGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+		frame.SetColorIndex(i, i, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	// a comment extension before the trailer
+	data := buf.Bytes()
+	data = append(data[:len(data)-1:len(data)-1], 0x21, 0xfe, 6)
+	data = append(data, "secret"...)
+	data = append(data, 0x00, 0x3b)
+
+	out, err := reencodeGIF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(out, []byte("secret")) {
+		t.Error("comment was kept")
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 3 || decoded.Delay[2] != 10 {
+		t.Errorf("%v frames with delays %v, want 3 frames of 10", len(decoded.Image), decoded.Delay)
+	}
+
+	if _, err := reencodeGIF([]byte("GIF89a")); err != ErrInvalidImage {
+		t.Errorf("truncated gif: err = %v, want %v", err, ErrInvalidImage)
+	}
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// SigningKey is a key access tokens are signed or verified with, identified
+// by the kid header of the tokens. Retired keys have no Private key, they
+// only verify the tokens issued before a rotation.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeyRing holds the keys of access tokens: the active one, which signs, and
+// the others, which still verify.
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// legacy accepts tokens signed with API_SECRET, which have no kid
+	legacy bool
+}
+
+var (
+	keyRingMu sync.RWMutex
+	keyRing   *KeyRing
+)
+
+// NewKeyRing returns a ring of keys signing with the key activeID. legacy
+// keeps accepting the HS256 tokens signed with API_SECRET, while switching
+// to asymmetric keys.
+func NewKeyRing(keys []*SigningKey, activeID string, legacy bool) (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string]*SigningKey{}, legacy: legacy}
+
+	for _, key := range keys {
+		if _, ok := ring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("unknown active key %q", activeID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// SetKeyRing replaces the keys of access tokens. Without a ring, tokens are
+// signed with API_SECRET.
+func SetKeyRing(ring *KeyRing) {
+	keyRingMu.Lock()
+	defer keyRingMu.Unlock()
+
+	keyRing = ring
+}
+
+func currentKeyRing() *KeyRing {
+	keyRingMu.RLock()
+	defer keyRingMu.RUnlock()
+
+	return keyRing
+}
+
+// signToken signs claims with the active key, or API_SECRET without a ring.
+func signToken(claims jwt.Claims) (string, error) {
+	ring := currentKeyRing()
+	if ring == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(APISecret()))
+	}
+
+	token := jwt.NewWithClaims(ring.active.Method, claims)
+	token.Header["kid"] = ring.active.ID
+
+	return token.SignedString(ring.active.Private)
+}
+
+// verificationKey is the jwt.Keyfunc of access tokens. The algorithm must
+// be the one of the key, so a public key can't be used as an HMAC secret.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	ring := currentKeyRing()
+	kid, _ := token.Header["kid"].(string)
+
+	if kid == "" && (ring == nil || ring.legacy) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(APISecret()), nil
+	}
+
+	if ring == nil {
+		return nil, errors.New("unknown key")
+	}
+
+	key, ok := ring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.Public, nil
+}
+
+// JSONWebKey is a public key in the JWK format, RFC 7517.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeys returns the public keys of the ring, sorted by kid, for other
+// services to verify access tokens. It is empty without a ring, HS256
+// secrets can't be published.
+func JSONWebKeys() []JSONWebKey {
+	jwks := []JSONWebKey{}
+
+	ring := currentKeyRing()
+	if ring == nil {
+		return jwks
+	}
+
+	for _, key := range ring.keys {
+		jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+
+	return jwks
+}
+
+// ParseSigningKey reads the PEM encoded key id, an RSA or Ed25519 private
+// key, or only its public key for a retired key.
+func ParseSigningKey(id string, pem []byte) (*SigningKey, error) {
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	}
+
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: private.(ed25519.PrivateKey).Public()}, nil
+	}
+
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Public: public}, nil
+	}
+
+	if public, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Public: public}, nil
+	}
+
+	return nil, fmt.Errorf("key %q is not an RSA or Ed25519 key", id)
+}
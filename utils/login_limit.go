@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+)
+
+// LoginLimit is how many failed logins are tolerated before locking out an
+// account or an IP, and for how long.
+type LoginLimit struct {
+	AccountAttempts int
+	IPAttempts      int
+	// Lockout is the first lockout, it doubles with every further failure
+	// up to MaxLockout. Failures are forgotten after MaxLockout without any.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+func LoginLimits() (LoginLimit, error) {
+	var limit LoginLimit
+	var err error
+
+	if limit.AccountAttempts, err = strconv.Atoi(GetEnv("LOGIN_MAX_ATTEMPTS", "5")); err != nil {
+		return limit, err
+	}
+
+	if limit.IPAttempts, err = strconv.Atoi(GetEnv("LOGIN_IP_MAX_ATTEMPTS", "20")); err != nil {
+		return limit, err
+	}
+
+	seconds, err := strconv.Atoi(GetEnv("LOGIN_LOCKOUT_SECONDS", "30"))
+	if err != nil {
+		return limit, err
+	}
+
+	minutes, err := strconv.Atoi(GetEnv("LOGIN_LOCKOUT_MAX_MINUTES", "60"))
+	if err != nil {
+		return limit, err
+	}
+
+	limit.Lockout = time.Second * time.Duration(seconds)
+	limit.MaxLockout = time.Minute * time.Duration(minutes)
+
+	return limit, nil
+}
+
+// LockoutAfter returns how long to lock out after failures failed logins,
+// when attempts are allowed. It is zero below attempts.
+func (l LoginLimit) LockoutAfter(failures int, attempts int) time.Duration {
+	if failures < attempts {
+		return 0
+	}
+
+	lockout := l.Lockout
+	for i := attempts; i < failures && lockout < l.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	if lockout > l.MaxLockout {
+		return l.MaxLockout
+	}
+
+	return lockout
+}
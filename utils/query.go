@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"encoding/base64"
+	"final-project/apperrors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPerPage = 10
+	maxPerPage     = 100
+)
+
+// Filter maps a query string parameter to a column. Like filters match
+// case-insensitively on a substring, the others on equality.
+type Filter struct {
+	Param  string
+	Column string
+	Like   bool
+}
+
+// QueryOptions whitelists what a list endpoint can be sorted and filtered by.
+type QueryOptions struct {
+	Sorts       []string
+	DefaultSort string
+	Filters     []Filter
+}
+
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListQuery is a parsed and validated list request, see ParseListQuery.
+type ListQuery struct {
+	Page     int
+	PerPage  int
+	Cursor   *uint
+	IsCursor bool
+	Sort     []string
+	Filters  map[string]string
+	options  QueryOptions
+}
+
+// ParseListQuery reads page/per_page (or cursor), sort and filter parameters
+// from the request. Sending the cursor parameter, even empty, switches to
+// cursor pagination, which always orders by id.
+func ParseListQuery(c *gin.Context, opts QueryOptions) (*ListQuery, error) {
+	q := &ListQuery{
+		Page:    1,
+		PerPage: defaultPerPage,
+		Filters: map[string]string{},
+		options: opts,
+	}
+
+	if v := c.Query("per_page"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return nil, apperrors.BadRequest("INVALID_PARAM", "param", "per_page")
+		}
+		q.PerPage = int(math.Min(float64(perPage), maxPerPage))
+	}
+
+	if v, ok := c.GetQuery("cursor"); ok {
+		q.IsCursor = true
+
+		if v != "" {
+			id, err := decodeCursor(v)
+			if err != nil {
+				return nil, apperrors.BadRequest("INVALID_PARAM", "param", "cursor")
+			}
+			q.Cursor = &id
+		}
+	} else if v := c.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, apperrors.BadRequest("INVALID_PARAM", "param", "page")
+		}
+		q.Page = page
+	}
+
+	sort := c.DefaultQuery("sort", opts.DefaultSort)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !slices.Contains(opts.Sorts, strings.TrimPrefix(field, "-")) {
+			return nil, apperrors.BadRequest("SORT_UNSUPPORTED", "sort", field)
+		}
+		q.Sort = append(q.Sort, field)
+	}
+
+	if q.IsCursor {
+		if len(q.Sort) > 1 || (len(q.Sort) == 1 && strings.TrimPrefix(q.Sort[0], "-") != "id") {
+			return nil, apperrors.BadRequest("CURSOR_SORT_UNSUPPORTED")
+		}
+		if len(q.Sort) == 0 {
+			q.Sort = []string{"-id"}
+		}
+	}
+
+	for _, f := range opts.Filters {
+		if v := c.Query(f.Param); v != "" {
+			q.Filters[f.Param] = v
+		}
+	}
+
+	return q, nil
+}
+
+// Find applies the filters, sort and pagination to db, loads the page into
+// dest (a pointer to a slice of models) and returns the pagination metadata.
+func (q *ListQuery) Find(db *gorm.DB, dest interface{}) (*Pagination, error) {
+	db = db.Model(dest)
+
+	for _, f := range q.options.Filters {
+		v, ok := q.Filters[f.Param]
+		if !ok {
+			continue
+		}
+
+		if f.Like {
+			db = db.Where(f.Column+" ILIKE ?", "%"+v+"%")
+		} else {
+			db = db.Where(f.Column+" = ?", v)
+		}
+	}
+
+	meta := &Pagination{PerPage: q.PerPage}
+
+	if err := db.Session(&gorm.Session{}).Count(&meta.Total).Error; err != nil {
+		return nil, err
+	}
+
+	for _, field := range q.Sort {
+		if strings.HasPrefix(field, "-") {
+			db = db.Order(strings.TrimPrefix(field, "-") + " DESC")
+		} else {
+			db = db.Order(field + " ASC")
+		}
+	}
+
+	if !q.IsCursor {
+		err := db.Offset(q.Offset()).Limit(q.PerPage).Find(dest).Error
+		return q.Pagination(meta.Total), err
+	}
+
+	if q.Cursor != nil {
+		if strings.HasPrefix(q.Sort[0], "-") {
+			db = db.Where("id < ?", *q.Cursor)
+		} else {
+			db = db.Where("id > ?", *q.Cursor)
+		}
+	}
+
+	if err := db.Limit(q.PerPage).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if rows.Len() == q.PerPage {
+		last := reflect.Indirect(rows.Index(rows.Len() - 1))
+		meta.NextCursor = encodeCursor(uint(last.FieldByName("ID").Uint()))
+	}
+
+	return meta, nil
+}
+
+func (q *ListQuery) Offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+// Pagination builds the page based metadata for a result of total rows.
+func (q *ListQuery) Pagination(total int64) *Pagination {
+	return &Pagination{
+		Page:       q.Page,
+		PerPage:    q.PerPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(q.PerPage))),
+	}
+}
+
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseUint(string(b), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
@@ -1,13 +1,37 @@
 package utils
 
 import (
+	"errors"
+	"final-project/apperrors"
+	"log"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// CreateResponse sends data in the response envelope, messages translated
+// to the request language. Errors are sent by CreateErrorResponse, which
+// decides their status.
 func CreateResponse(c *gin.Context, status int, data interface{}) {
+	if err, ok := data.(error); ok {
+		CreateErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(status, createEnvelope(status, translate(c, data)))
+}
+
+// CreatePaginatedResponse is CreateResponse for list endpoints, it adds the
+// pagination metadata under "meta".
+func CreatePaginatedResponse(c *gin.Context, status int, data interface{}, meta *Pagination) {
+	res := createEnvelope(status, translate(c, data))
+	res["meta"] = meta
+
+	c.JSON(status, res)
+}
+
+func createEnvelope(status int, data interface{}) gin.H {
 	ok := false
 	msg := "Failed"
 
@@ -18,7 +42,7 @@ func CreateResponse(c *gin.Context, status int, data interface{}) {
 		msg = "Success"
 	}
 
-	var res map[string]interface{}
+	var res gin.H
 
 	if ok {
 		res = gin.H{
@@ -36,5 +60,52 @@ func CreateResponse(c *gin.Context, status int, data interface{}) {
 		}
 	}
 
-	c.JSON(status, res)
+	return res
+}
+
+// CreateErrorResponse sends err with the HTTP status of its apperrors kind
+// and its code. Field errors are input errors. Any other error is logged and
+// sent as an internal error, its text may reveal database details.
+func CreateErrorResponse(c *gin.Context, err error) {
+	lang := Language(c)
+	c.Header("Content-Language", lang)
+
+	var appErr *apperrors.Error
+	var fieldErrs FieldErrors
+
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &fieldErrs):
+		appErr = apperrors.Validation(fieldErrs)
+	default:
+		appErr = apperrors.Internal(err)
+	}
+
+	var data interface{} = NewMessage(appErr.MessageKey(), appErr.Params...).Translate(lang)
+
+	switch appErr.Kind {
+	case apperrors.KindInternal:
+		log.Printf("%v %v: %v", c.Request.Method, c.Request.URL.Path, err)
+	case apperrors.KindValidation:
+		if errors.As(appErr.Err, &fieldErrs) {
+			data = fieldErrs.Translate(lang)
+		}
+	}
+
+	res := createEnvelope(appErr.Status(), data)
+	res["code"] = appErr.Code
+
+	c.JSON(appErr.Status(), res)
+}
+
+// translate returns data with its messages in the request language.
+func translate(c *gin.Context, data interface{}) interface{} {
+	lang := Language(c)
+	c.Header("Content-Language", lang)
+
+	if msg, ok := data.(*Message); ok {
+		return msg.Translate(lang)
+	}
+
+	return data
 }
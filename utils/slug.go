@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxSlugLength = 100
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// slugTransliterations covers the latin letters that do not decompose into
+// an ASCII letter and combining marks.
+var slugTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "ae", 'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o", 'đ': "d", 'Đ': "d", 'ð': "d", 'Ð': "d",
+	'ł': "l", 'Ł': "l", 'þ': "th", 'Þ': "th", 'ı': "i",
+}
+
+// Slugify turns s into a URL safe slug: lowercase ASCII letters and digits
+// separated by single hyphens, at most MaxSlugLength long. Accented letters
+// lose their accents, apostrophes are dropped and anything else separates
+// words. The result is empty when s has no letter or digit to keep.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range norm.NFKD.String(s) {
+		if t, ok := slugTransliterations[r]; ok {
+			b.WriteString(t)
+			hyphen = false
+			continue
+		}
+
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			// combining marks left by the decomposition, apostrophes
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(unicode.ToLower(r))
+			hyphen = false
+		case b.Len() > 0 && !hyphen:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	return TruncateSlug(strings.TrimSuffix(b.String(), "-"), MaxSlugLength)
+}
+
+// TruncateSlug shortens slug to at most max bytes, cutting at a hyphen when
+// there is one.
+func TruncateSlug(slug string, max int) string {
+	if len(slug) <= max {
+		return slug
+	}
+
+	slug = slug[:max]
+	if i := strings.LastIndexByte(slug, '-'); i > 0 {
+		slug = slug[:i]
+	}
+
+	return strings.TrimSuffix(slug, "-")
+}
+
+func IsSlug(s string) bool {
+	return len(s) <= MaxSlugLength && slugPattern.MatchString(s)
+}
@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"final-project/apperrors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,11 +15,22 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-var API_SECRET = GetEnv("API_SECRET", "secret")
-var TOKEN_HOUR_LIFESPAN = GetEnv("TOKEN_HOUR_LIFESPAN", "24")
+// APISecret is read when used rather than at package initialization, which
+// runs before main loads .env.
+func APISecret() string {
+	return GetEnv("API_SECRET", "secret")
+}
+
+var (
+	ErrInvalidToken = apperrors.Unauthorized("INVALID_TOKEN")
+	ErrExpiredToken = apperrors.Unauthorized("TOKEN_EXPIRED")
+)
 
-func GenerateToken(uid uint) (string, error) {
-	tokenLife, err := strconv.Atoi(TOKEN_HOUR_LIFESPAN)
+// GenerateToken creates a short-lived access token bound to the session it
+// was issued for, so the session can be revoked server-side. It is signed
+// with the active key of the key ring, see SetKeyRing.
+func GenerateToken(uid uint, sid uint) (string, error) {
+	tokenLife, err := strconv.Atoi(GetEnv("TOKEN_MINUTE_LIFESPAN", "15"))
 
 	if err != nil {
 		return "", err
@@ -23,26 +39,52 @@ func GenerateToken(uid uint) (string, error) {
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["user_id"] = uid
-	claims["exp"] = time.Now().Add(time.Hour * time.Duration(tokenLife)).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	claims["session_id"] = sid
+	claims["exp"] = time.Now().Add(time.Minute * time.Duration(tokenLife)).Unix()
 
-	return token.SignedString([]byte(API_SECRET))
+	return signToken(claims)
 }
 
-func TokenValid(c *gin.Context) error {
-	tokenStr := ExtractToken(c)
+// GenerateRefreshToken creates an opaque random refresh token. Only its hash
+// is stored, see HashToken.
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func RefreshTokenLifespan() (time.Duration, error) {
+	tokenLife, err := strconv.Atoi(GetEnv("REFRESH_TOKEN_HOUR_LIFESPAN", "24"))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Hour * time.Duration(tokenLife), nil
+}
+
+func AccessTokenLifespan() (time.Duration, error) {
+	tokenLife, err := strconv.Atoi(GetEnv("TOKEN_MINUTE_LIFESPAN", "15"))
 
-	_, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(API_SECRET), nil
-	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return time.Minute * time.Duration(tokenLife), nil
+}
+
+func TokenValid(c *gin.Context) error {
+	_, err := ExtractTokenClaims(c)
+	return err
 }
 
 func ExtractToken(c *gin.Context) string {
@@ -58,24 +100,38 @@ func ExtractToken(c *gin.Context) string {
 	return ""
 }
 
-func ExtractTokenID(c *gin.Context) (uint, error) {
+func ExtractTokenClaims(c *gin.Context) (jwt.MapClaims, error) {
 	tokenString := ExtractToken(c)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	token, err := jwt.Parse(tokenString, verificationKey)
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrExpiredToken
 		}
-		return []byte(API_SECRET), nil
-	})
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func ExtractTokenSessionID(c *gin.Context) (uint, error) {
+	claims, err := ExtractTokenClaims(c)
 	if err != nil {
 		return 0, err
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["user_id"]), 10, 32)
-		if err != nil {
-			return 0, err
-		}
-		return uint(uid), nil
+	return claimToUint(claims, "session_id")
+}
+
+func claimToUint(claims jwt.MapClaims, key string) (uint, error) {
+	if _, ok := claims[key]; !ok {
+		return 0, ErrInvalidToken
+	}
+	id, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims[key]), 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
 	}
-	return 0, nil
+	return uint(id), nil
 }
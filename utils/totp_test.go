@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, the last 6 of the 8 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("TOTPCode at %v = %v, want %v", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+
+	for _, tt := range tests {
+		code, _ := TOTPCode(rfcSecret, step+tt.offset)
+
+		matched, ok := ValidateTOTP(rfcSecret, code, now, 0)
+		if ok != tt.ok {
+			t.Errorf("code of step %+d: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+		if ok && matched != step+tt.offset {
+			t.Errorf("code of step %+d matched step %v", tt.offset, matched-step)
+		}
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+	code, _ := TOTPCode(rfcSecret, step)
+
+	if _, ok := ValidateTOTP(rfcSecret, code, now, step-1); !ok {
+		t.Error("unused code was refused")
+	}
+
+	if _, ok := ValidateTOTP(rfcSecret, code, now, step); ok {
+		t.Error("code of the last used step was accepted")
+	}
+
+	// a code of an earlier step, still in the window, can't follow a later one
+	previous, _ := TOTPCode(rfcSecret, step-1)
+	if _, ok := ValidateTOTP(rfcSecret, previous, now, step); ok {
+		t.Error("code older than the last used step was accepted")
+	}
+}
+
+func TestValidateTOTPMalformed(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	for _, code := range []string{"", "00592", "0059240", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now, 0); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(code) != 11 || code[5] != '-' {
+		t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+	}
+
+	want := code[:5] + code[6:]
+	for _, typed := range []string{code, want, " " + code[:5] + " " + code[6:] + " ", strings.ToUpper(code)} {
+		if got := NormalizeRecoveryCode(typed); got != want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, got, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is an input error tied to a request field. Code is stable and
+// meant for clients localizing the message themselves, Param completes it,
+// e.g. the minimum length of a "min" error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+// FieldErrors are the input errors of a request, returned by BindJSON and
+// the model Validate methods. Unlike other errors they are meant to be shown
+// to the client.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// NewFieldError returns the error code for field, its message is in
+// DefaultLanguage until CreateResponse translates it.
+func NewFieldError(field, code, param string) FieldError {
+	e := FieldError{Field: field, Code: code, Param: param}
+	e.Message = e.message().Translate(DefaultLanguage)
+	return e
+}
+
+func (e FieldError) message() *Message {
+	key := "validation." + e.Code
+	if _, ok := messages[DefaultLanguage][key]; !ok {
+		key = "validation.invalid"
+	}
+
+	return NewMessage(key, "field", e.Field, "param", e.Param)
+}
+
+// Translate returns the errors with their messages in lang.
+func (e FieldErrors) Translate(lang string) FieldErrors {
+	translated := FieldErrors{}
+	for _, err := range e {
+		err.Message = err.message().Translate(lang)
+		translated = append(translated, err)
+	}
+	return translated
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// report fields by their JSON name
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return IsSlug(fl.Field().String())
+	})
+
+	v.RegisterValidation("httpurl", func(fl validator.FieldLevel) bool {
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	})
+
+	v.RegisterValidation("future", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		return ok && t.After(time.Now())
+	})
+
+	v.RegisterValidation("ids", func(fl validator.FieldLevel) bool {
+		for _, id := range strings.Split(fl.Field().String(), ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// BindJSON decodes the request body into obj and checks it against the
+// binding tags of its fields.
+func BindJSON(c *gin.Context, obj interface{}) FieldErrors {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		errs := FieldErrors{}
+		for _, e := range validationErrs {
+			errs = append(errs, NewFieldError(e.Field(), e.Tag(), e.Param()))
+		}
+		return errs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldErrors{NewFieldError(typeErr.Field, "type", "")}
+	}
+
+	var timeErr *time.ParseError
+	if errors.As(err, &timeErr) {
+		return FieldErrors{NewFieldError("", "time", time.RFC3339)}
+	}
+
+	return FieldErrors{NewFieldError("", "json", "")}
+}
@@ -0,0 +1,573 @@
+package utils
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// EncodeWebP writes img as a lossless WebP (VP8L) image. The encoder is kept
+// simple: subtract green and predictor transforms, prefix coded literals and
+// run length backward references, no color cache.
+//
+// See https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return errors.New("webp: invalid image size")
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+
+	for i := range argb {
+		p := nrgba.Pix[i*4 : i*4+4]
+		argb[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+
+		if p[3] != 0xff {
+			hasAlpha = true
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+
+	// subtract green
+	bw.write(1, 1)
+	bw.write(2, 2)
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p>>16)&0xff - g) & 0xff
+		b := (p&0xff - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+
+	// predictor
+	modes, residuals := predict(argb, width, height)
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(webpTileBits-2, 3)
+	writeImageData(bw, modes, false)
+
+	bw.write(0, 1)
+	writeImageData(bw, residuals, true)
+
+	data := bw.bytes()
+	size := len(data)
+	pad := size & 1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+size+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(size))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+const (
+	webpTileBits        = 4
+	webpMaxRunLength    = 4096
+	webpMinRunLength    = 3
+	webpLengthCodes     = 24
+	webpDistanceCodes   = 40
+	webpMaxCodeLength   = 15
+	webpMaxCLCodeLength = 7
+)
+
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// webpPredictors are the predictor modes tried for each tile. Modes using
+// the top right pixel are left out to keep the edge cases simple.
+var webpPredictors = []uint32{1, 2, 7, 11, 12}
+
+// predict picks the predictor mode with the smallest residuals for each
+// tile and returns the tile modes as the predictor sub image and the
+// residual image.
+func predict(argb []uint32, width, height int) ([]uint32, []uint32) {
+	tilesX := (width + 1<<webpTileBits - 1) >> webpTileBits
+	tilesY := (height + 1<<webpTileBits - 1) >> webpTileBits
+	modes := make([]uint32, tilesX*tilesY)
+	residuals := make([]uint32, len(argb))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := webpPredictors[0], -1
+
+			for _, mode := range webpPredictors {
+				cost := 0
+
+				for y := ty << webpTileBits; y < height && y < (ty+1)<<webpTileBits; y++ {
+					for x := tx << webpTileBits; x < width && x < (tx+1)<<webpTileBits; x++ {
+						cost += residualCost(argb[y*width+x], predictPixel(argb, width, x, y, mode))
+					}
+				}
+
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesX+tx] = 0xff000000 | best<<8
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := modes[(y>>webpTileBits)*tilesX+x>>webpTileBits] >> 8 & 0xff
+			i := y*width + x
+			residuals[i] = subPixels(argb[i], predictPixel(argb, width, x, y, mode))
+		}
+	}
+
+	return modes, residuals
+}
+
+func predictPixel(argb []uint32, width, x, y int, mode uint32) uint32 {
+	i := y*width + x
+
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+
+	l, t, tl := argb[i-1], argb[i-width], argb[i-width-1]
+
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	}
+
+	var out uint32
+
+	if mode == 11 {
+		pl, pt := 0, 0
+		for shift := 0; shift < 32; shift += 8 {
+			pl += absInt(channel(tl, shift) - channel(t, shift))
+			pt += absInt(channel(tl, shift) - channel(l, shift))
+		}
+		if pl < pt {
+			return l
+		}
+		return t
+	}
+
+	for shift := 0; shift < 32; shift += 8 {
+		var v int
+		if mode == 7 {
+			v = (channel(l, shift) + channel(t, shift)) / 2
+		} else {
+			v = channel(l, shift) + channel(t, shift) - channel(tl, shift)
+			if v < 0 {
+				v = 0
+			} else if v > 255 {
+				v = 255
+			}
+		}
+		out |= uint32(v) << shift
+	}
+
+	return out
+}
+
+func subPixels(a, b uint32) uint32 {
+	var out uint32
+	for shift := 0; shift < 32; shift += 8 {
+		out |= uint32((channel(a, shift)-channel(b, shift))&0xff) << shift
+	}
+	return out
+}
+
+func residualCost(p, pred uint32) int {
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		cost += absInt(int(int8((channel(p, shift) - channel(pred, shift)) & 0xff)))
+	}
+	return cost
+}
+
+func channel(p uint32, shift int) int {
+	return int(p >> shift & 0xff)
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// webpToken is a literal pixel or, when length > 0, a copy of the previous
+// pixel repeated length times.
+type webpToken struct {
+	argb   uint32
+	length int
+}
+
+// writeImageData entropy codes pix, the main image or a transform sub image.
+func writeImageData(bw *bitWriter, pix []uint32, main bool) {
+	// no color cache
+	bw.write(0, 1)
+	if main {
+		// a single set of prefix codes for the whole image
+		bw.write(0, 1)
+	}
+
+	tokens := []webpToken{}
+	for i := 0; i < len(pix); {
+		run := 0
+		for i > 0 && i+run < len(pix) && run < webpMaxRunLength && pix[i+run] == pix[i-1] {
+			run++
+		}
+
+		if run >= webpMinRunLength {
+			tokens = append(tokens, webpToken{length: run})
+			i += run
+			continue
+		}
+
+		tokens = append(tokens, webpToken{argb: pix[i]})
+		i++
+	}
+
+	green := make([]uint32, 256+webpLengthCodes)
+	red := make([]uint32, 256)
+	blue := make([]uint32, 256)
+	alpha := make([]uint32, 256)
+	distance := make([]uint32, webpDistanceCodes)
+
+	// distance code 2 is the pixel on the left, prefix coded as symbol 1
+	const leftDistanceSymbol = 1
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			symbol, _, _ := prefixEncode(t.length)
+			green[256+symbol]++
+			distance[leftDistanceSymbol]++
+			continue
+		}
+
+		green[t.argb>>8&0xff]++
+		red[t.argb>>16&0xff]++
+		blue[t.argb&0xff]++
+		alpha[t.argb>>24]++
+	}
+
+	codes := []*prefixCode{}
+	for _, histogram := range [][]uint32{green, red, blue, alpha, distance} {
+		code := newPrefixCode(histogram, webpMaxCodeLength)
+		code.writeTo(bw)
+		codes = append(codes, code)
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			symbol, extraBits, extra := prefixEncode(t.length)
+			codes[0].writeSymbol(bw, 256+symbol)
+			bw.write(extra, extraBits)
+			codes[4].writeSymbol(bw, leftDistanceSymbol)
+			continue
+		}
+
+		codes[0].writeSymbol(bw, int(t.argb>>8&0xff))
+		codes[1].writeSymbol(bw, int(t.argb>>16&0xff))
+		codes[2].writeSymbol(bw, int(t.argb&0xff))
+		codes[3].writeSymbol(bw, int(t.argb>>24))
+	}
+}
+
+// prefixEncode splits a backward reference length or distance into its
+// prefix symbol and extra bits.
+func prefixEncode(v int) (int, uint, uint32) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+
+	highest := 0
+	for v>>(highest+1) != 0 {
+		highest++
+	}
+
+	second := v >> (highest - 1) & 1
+	extraBits := uint(highest - 1)
+
+	return 2*highest + second, extraBits, uint32(v & (1<<extraBits - 1))
+}
+
+// prefixCode is a canonical Huffman code. lengths are the code lengths sent
+// in the bitstream, bits those written per symbol: they differ for a code
+// with a single symbol, which takes no bits at all.
+type prefixCode struct {
+	lengths []uint8
+	bits    []uint8
+	codes   []uint32
+}
+
+func newPrefixCode(histogram []uint32, maxLength int) *prefixCode {
+	lengths := huffmanLengths(histogram, maxLength)
+	code := &prefixCode{
+		lengths: lengths,
+		bits:    append([]uint8{}, lengths...),
+		codes:   make([]uint32, len(lengths)),
+	}
+
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+
+	if used == 1 {
+		for i := range code.bits {
+			code.bits[i] = 0
+		}
+		return code
+	}
+
+	count := make([]uint32, maxLength+1)
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	next := make([]uint32, maxLength+2)
+	for l := 1; l <= maxLength; l++ {
+		next[l+1] = (next[l] + count[l]) << 1
+	}
+
+	for symbol, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		// codes are read bit by bit from the least significant bit
+		code.codes[symbol] = reverseBits(next[l], uint(l))
+		next[l]++
+	}
+
+	return code
+}
+
+func (p *prefixCode) writeSymbol(bw *bitWriter, symbol int) {
+	bw.write(p.codes[symbol], uint(p.bits[symbol]))
+}
+
+// writeTo writes the code lengths, themselves prefix coded.
+func (p *prefixCode) writeTo(bw *bitWriter) {
+	used := 0
+	for _, l := range p.lengths {
+		if l > 0 {
+			used++
+		}
+	}
+
+	if used == 0 {
+		// simple code with the single, never used, symbol 0
+		bw.write(1, 1)
+		bw.write(0, 1)
+		bw.write(0, 1)
+		bw.write(0, 1)
+		return
+	}
+
+	bw.write(0, 1)
+
+	// runs of zero lengths use codes 17 (3 to 10) and 18 (11 to 138)
+	type lengthSymbol struct {
+		symbol    int
+		extraBits uint
+		extra     uint32
+	}
+
+	symbols := []lengthSymbol{}
+	for i := 0; i < len(p.lengths); {
+		run := 0
+		for i+run < len(p.lengths) && run < 138 && p.lengths[i+run] == 0 {
+			run++
+		}
+
+		switch {
+		case run >= 11:
+			symbols = append(symbols, lengthSymbol{18, 7, uint32(run - 11)})
+			i += run
+		case run >= 3:
+			symbols = append(symbols, lengthSymbol{17, 3, uint32(run - 3)})
+			i += run
+		default:
+			symbols = append(symbols, lengthSymbol{symbol: int(p.lengths[i])})
+			i++
+		}
+	}
+
+	histogram := make([]uint32, 19)
+	for _, s := range symbols {
+		histogram[s.symbol]++
+	}
+	lengthCode := newPrefixCode(histogram, webpMaxCLCodeLength)
+
+	n := len(webpCodeLengthOrder)
+	for n > 4 && lengthCode.lengths[webpCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+
+	bw.write(uint32(n-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:n] {
+		bw.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+
+	// code lengths for the whole alphabet follow
+	bw.write(0, 1)
+	for _, s := range symbols {
+		lengthCode.writeSymbol(bw, s.symbol)
+		bw.write(s.extra, s.extraBits)
+	}
+}
+
+// huffmanLengths returns the Huffman code lengths for histogram, at most
+// maxLength bits. Counts are flattened until the tree is shallow enough.
+func huffmanLengths(histogram []uint32, maxLength int) []uint8 {
+	lengths := make([]uint8, len(histogram))
+	counts := append([]uint32{}, histogram...)
+
+	for {
+		h := &huffmanHeap{}
+		for symbol, count := range counts {
+			if count > 0 {
+				*h = append(*h, &huffmanNode{weight: count, symbol: symbol})
+			}
+		}
+
+		switch h.Len() {
+		case 0:
+			return lengths
+		case 1:
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+
+		heap.Init(h)
+
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffmanNode)
+			b := heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{weight: a.weight + b.weight, symbol: -1, left: a, right: b})
+		}
+
+		tooLong := false
+		var walk func(n *huffmanNode, depth int)
+		walk = func(n *huffmanNode, depth int) {
+			if n.left == nil {
+				if depth > maxLength {
+					tooLong = true
+				}
+				lengths[n.symbol] = uint8(depth)
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk((*h)[0], 0)
+
+		if !tooLong {
+			return lengths
+		}
+
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = count>>1 | 1
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	weight      uint32
+	symbol      int
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+func reverseBits(v uint32, n uint) uint32 {
+	var out uint32
+	for i := uint(0); i < n; i++ {
+		out = out<<1 | v&1
+		v >>= 1
+	}
+	return out
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.nacc
+	b.nacc += n
+
+	for b.nacc >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nacc -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.nacc > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nacc = 0, 0
+	}
+	return b.buf
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testImage fills a w x h image with gradients, solid runs and noise, so the
+// predictors, backward references and literals all get used.
+func testImage(w, h int, alpha bool, seed int64) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(seed))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{A: 0xff}
+			switch {
+			case y < h/3:
+				c.R, c.G, c.B = uint8(x*7), uint8(y*5), uint8(x+y)
+			case y < 2*h/3:
+				c.R, c.G, c.B = 0x20, 0x80, 0xc0
+			default:
+				c.R, c.G, c.B = uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256))
+			}
+			if alpha {
+				c.A = uint8(rnd.Intn(256))
+				if x%5 == 0 {
+					c.A = 0
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	sizes := [][2]int{{1, 1}, {1, 7}, {7, 1}, {3, 5}, {17, 9}, {64, 64}, {257, 3}, {101, 99}}
+
+	for i, size := range sizes {
+		for _, alpha := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%vx%v alpha %v", size[0], size[1], alpha), func(t *testing.T) {
+				img := testImage(size[0], size[1], alpha, int64(i))
+
+				var buf bytes.Buffer
+				if err := EncodeWebP(&buf, img); err != nil {
+					t.Fatal(err)
+				}
+
+				decoded, err := webp.Decode(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if decoded.Bounds() != img.Bounds() {
+					t.Fatalf("bounds = %v, want %v", decoded.Bounds(), img.Bounds())
+				}
+
+				for y := 0; y < size[1]; y++ {
+					for x := 0; x < size[0]; x++ {
+						want := img.NRGBAAt(x, y)
+						got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+						if got != want {
+							t.Fatalf("pixel (%v, %v) = %v, want %v", x, y, got, want)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeWebPSubImage(t *testing.T) {
+	img := testImage(20, 20, true, 1)
+	sub := img.SubImage(image.Rect(5, 3, 16, 12)).(*image.NRGBA)
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			want := sub.NRGBAAt(x+5, y+3)
+			got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			if got != want {
+				t.Fatalf("pixel (%v, %v) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 0, 1), image.Rect(0, 0, 1<<14+1, 1)} {
+		if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(r)); err == nil {
+			t.Errorf("%v: no error", r)
+		}
+	}
+}
+
+// TestEncodeWebPFewColors covers prefix codes of a single symbol and of two.
+func TestEncodeWebPFewColors(t *testing.T) {
+	colors := [][]color.NRGBA{
+		{{R: 0x12, G: 0x34, B: 0x56, A: 0xff}},
+		{{A: 0}},
+		{{R: 0xff, A: 0xff}, {B: 0xff, A: 0x80}},
+	}
+
+	for _, palette := range colors {
+		for _, size := range [][2]int{{1, 1}, {2, 2}, {40, 30}} {
+			img := image.NewNRGBA(image.Rect(0, 0, size[0], size[1]))
+			for i := 0; i < size[0]*size[1]; i++ {
+				img.SetNRGBA(i%size[0], i/size[0], palette[i%len(palette)])
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, img); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("%v colors %vx%v: %v", len(palette), size[0], size[1], err)
+			}
+
+			for i := 0; i < size[0]*size[1]; i++ {
+				x, y := i%size[0], i/size[0]
+				got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+				if want := img.NRGBAAt(x, y); got != want {
+					t.Fatalf("%v colors %vx%v: pixel (%v, %v) = %v, want %v", len(palette), size[0], size[1], x, y, got, want)
+				}
+			}
+		}
+	}
+}
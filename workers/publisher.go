@@ -0,0 +1,60 @@
+package workers
+
+import (
+	"context"
+	"final-project/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Publisher publishes scheduled articles once their publish_at has passed.
+type Publisher struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// defaultInterval replaces an interval of zero or less, which NewTicker
+// doesn't accept.
+const defaultInterval = 60 * time.Second
+
+func NewPublisher(db *gorm.DB, interval time.Duration) *Publisher {
+	if interval <= 0 {
+		log.Printf("publisher: interval %v must be positive, using %v\n", interval, defaultInterval)
+		interval = defaultInterval
+	}
+
+	return &Publisher{db: db, interval: interval}
+}
+
+// Run checks for due articles every interval until ctx is cancelled. The
+// check in progress, if any, finishes before Run returns.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.publishDue()
+
+		select {
+		case <-ctx.Done():
+			log.Println("publisher: stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) publishDue() {
+	count, err := models.PublishDueArticles(p.db, time.Now())
+
+	if err != nil {
+		log.Println("publisher:", err.Error())
+		return
+	}
+
+	if count > 0 {
+		log.Printf("publisher: published %v scheduled article(s)\n", count)
+	}
+}